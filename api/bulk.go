@@ -0,0 +1,383 @@
+// File: bulk.go
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Default interval between checks of the native import status
+const defaultPollInterval = 2 * time.Second
+
+// Number of emails looked up with a single subscriber query
+const lookupBatchSize = 500
+
+// Status of Listmonk's native subscriber import
+type BulkImportStatus struct {
+	Name     string `json:"name"`
+	Total    int    `json:"total"`
+	Imported int    `json:"imported"`
+	// One of "none", "importing", "stopping", "stopped", "finished" or "failed"
+	Status string `json:"status"`
+}
+
+// Result of BulkImportSubscribersFromCSV
+type BulkImportResult struct {
+	// Number of subscribers uploaded and imported by Listmonk
+	Total    int
+	Imported int
+	// Rows rejected before uploading, if opts.ContinueOnError is set
+	Failed []RowResult
+	// Import logs of Listmonk
+	Logs string
+}
+
+// Import subscribers from a CSV file using Listmonk's native bulk import
+// instead of sending requests for every subscriber. The file is read like in
+// AddSubscribersFromCSVWithOptions and converted to Listmonk's import format.
+// As Listmonk replaces attributes of existing subscribers, their current
+// attributes are fetched first and merged with the imported ones, keeping
+// existing keys. Rows subscribing to different lists are uploaded as separate
+// imports. Waits until Listmonk finishes importing, checking its status every
+// opts.PollInterval, and returns the final counts.
+func (c *APIClient) BulkImportSubscribersFromCSV(path, list string, passwords map[string]string, opts ImportOptions) (*BulkImportResult, error) {
+	return c.BulkImportSubscribersFromCSVContext(context.Background(), path, list, passwords, opts)
+}
+
+// BulkImportSubscribersFromCSVContext is like BulkImportSubscribersFromCSV but uses ctx for all Listmonk requests.
+func (c *APIClient) BulkImportSubscribersFromCSVContext(ctx context.Context, path, list string, passwords map[string]string, opts ImportOptions) (*BulkImportResult, error) {
+	start := time.Now()
+	c.logInfo(ctx, "Bulk importing subscribers from CSV to Listmonk.", "path", path, "list", list)
+	rows, failed, err := c.readCSVRows(path, list, passwords, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkImportResult{Failed: failed}
+	groups := groupRowsByLists(rows)
+	for _, group := range groups {
+		status, logs, err := c.bulkImport(ctx, group, opts)
+		if err != nil {
+			return result, err
+		}
+		result.Total += status.Total
+		result.Imported += status.Imported
+		result.Logs += logs
+	}
+
+	c.logOK(ctx, start, "Bulk import finished.", "path", path, "total", result.Total, "imported", result.Imported, "failed", len(failed))
+	return result, nil
+}
+
+// Read and parse all rows of a CSV file. Rows of the same email are merged.
+// Rows that cannot be parsed are returned separately if opts.ContinueOnError
+// is set, otherwise the first one fails the import.
+func (c *APIClient) readCSVRows(path, list string, passwords map[string]string, opts ImportOptions) ([]importRow, []RowResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("%w: no records found", ErrValidation)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mapping := DefaultCSVMapping()
+	if opts.Mapping != nil {
+		mapping = *opts.Mapping
+	}
+	layout, err := mapping.resolve(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []importRow
+	var failed []RowResult
+	indices := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if isEmptyRecord(record) {
+			continue
+		}
+
+		row, err := layout.parse(record, list)
+		// Passwords are given for emails as they appear in the file
+		key := passwords[row.email]
+		row.key = &key
+		if err == nil {
+			var email string
+			email, err = c.checkEmail(row.email)
+			if err == nil {
+				row.email = email
+			}
+		}
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			if !opts.ContinueOnError {
+				return nil, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			failed = append(failed, RowResult{Line: line, Email: row.email, Status: RowFailed, Reason: err.Error(), Record: record})
+			continue
+		}
+
+		// Later rows of the same subscriber overwrite earlier attributes
+		if i, ok := indices[row.email]; ok {
+			rows[i] = mergeRows(rows[i], row)
+			continue
+		}
+		indices[row.email] = len(rows)
+		rows = append(rows, row)
+	}
+	return rows, failed, nil
+}
+
+// Merge two rows of the same subscriber
+func mergeRows(a, b importRow) importRow {
+	if b.name != "" {
+		a.name = b.name
+	}
+	for _, list := range b.lists {
+		if !slices.Contains(a.lists, list) {
+			a.lists = append(a.lists, list)
+		}
+	}
+	for key, value := range b.attrs {
+		a.attrs[key] = value
+	}
+	return a
+}
+
+// Group rows subscribing to the same lists, keeping the order of rows
+func groupRowsByLists(rows []importRow) [][]importRow {
+	var groups [][]importRow
+	indices := map[string]int{}
+	for _, row := range rows {
+		key := strings.Join(row.lists, "\x00")
+		i, ok := indices[key]
+		if !ok {
+			i = len(groups)
+			indices[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups
+}
+
+// Upload rows subscribing to the same lists to Listmonk's import endpoint and
+// wait until they are imported. Returns the final status and import logs.
+func (c *APIClient) bulkImport(ctx context.Context, rows []importRow, opts ImportOptions) (*BulkImportStatus, string, error) {
+	lists := rows[0].lists
+	listIDs, err := c.resolveListIDs(ctx, lists)
+	if err != nil {
+		return nil, "", err
+	}
+
+	existing, err := c.getSubscribersByEmails(ctx, mapping(rows, func(r importRow) string { return r.email }))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Listmonk's import format: email, name and attributes as a JSON object
+	var file bytes.Buffer
+	writer := csv.NewWriter(&file)
+	writer.Write([]string{"email", "name", "attributes"})
+	for _, row := range rows {
+		var current map[string]interface{}
+		name := row.name
+		if subscriber, ok := existing[lookupEmail(row.email)]; ok {
+			current = subscriber.Attributes
+			if name == "" {
+				name = subscriber.Name
+			}
+		}
+		if name == "" {
+			name = row.email
+		}
+
+		attrs, err := json.Marshal(row.merge()(current))
+		if err != nil {
+			return nil, "", err
+		}
+		writer.Write([]string{row.email, name, string(attrs)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, "", err
+	}
+
+	params := map[string]interface{}{
+		"mode":                "subscribe",
+		"subscription_status": "unconfirmed",
+		"delim":               ",",
+		"lists":               listIDs,
+		"overwrite":           true,
+	}
+	if c.dryRun(ctx, fmt.Sprintf("Bulk import %d subscribers to lists %s", len(rows), strings.Join(lists, ", ")), http.MethodPost, "/import/subscribers", params) {
+		return &BulkImportStatus{Total: len(rows), Status: "none"}, "", nil
+	}
+
+	err = c.prepareBulkImport(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c.logInfo(ctx, "Uploading subscribers to Listmonk import.", "subscribers", len(rows), "lists", lists)
+	err = c.uploadBulkImport(ctx, params, file.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	status, err := c.waitForBulkImport(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var logs string
+	err = c.callAPI(ctx, http.MethodGet, "/import/subscribers/logs", nil, &logs)
+	if err != nil {
+		return nil, "", err
+	}
+	if status.Status != "finished" {
+		return status, logs, fmt.Errorf("Listmonk import %s: %s", status.Status, strings.TrimSpace(logs))
+	}
+	return status, logs, nil
+}
+
+// Get subscribers with given emails, mapped by lowercased email
+func (c *APIClient) getSubscribersByEmails(ctx context.Context, emails []string) (map[string]*listmonk.Subscriber, error) {
+	subscribers := make(map[string]*listmonk.Subscriber, len(emails))
+	for len(emails) > 0 {
+		batch := emails[:min(lookupBatchSize, len(emails))]
+		emails = emails[len(batch):]
+		query, err := In(Lower(Column("subscribers.email")), mapping(batch, func(e string) interface{} { return lookupEmail(e) })...).Build()
+		if err != nil {
+			return nil, err
+		}
+
+		getSubscribersService := c.Client.NewGetSubscribersService()
+		getSubscribersService.Query(query)
+		getSubscribersService.PerPage("all")
+		found, err := apiResult(getSubscribersService.Do(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, subscriber := range found {
+			subscribers[lookupEmail(subscriber.Email)] = subscriber
+		}
+	}
+	return subscribers, nil
+}
+
+// Get status of Listmonk's native import
+func (c *APIClient) getBulkImportStatus(ctx context.Context) (*BulkImportStatus, error) {
+	var status BulkImportStatus
+	err := c.callAPI(ctx, http.MethodGet, "/import/subscribers", nil, &status)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Make sure no other import is running and clear the status of the previous
+// one, so that it is not mistaken for the result of a new import
+func (c *APIClient) prepareBulkImport(ctx context.Context) error {
+	status, err := c.getBulkImportStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch status.Status {
+	case "importing", "stopping":
+		return fmt.Errorf("%w: another import is running: %s", ErrConflict, status.Name)
+	case "finished", "failed", "stopped":
+		return c.callAPI(ctx, http.MethodDelete, "/import/subscribers", nil, nil)
+	}
+	return nil
+}
+
+// Upload a file in Listmonk's import format
+func (c *APIClient) uploadBulkImport(ctx context.Context, params map[string]interface{}, data []byte) error {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	err = writer.WriteField("params", string(paramsJSON))
+	if err != nil {
+		return err
+	}
+	part, err := writer.CreateFormFile("file", "subscribers.csv")
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.callAPIRaw(ctx, http.MethodPost, "/import/subscribers", writer.FormDataContentType(), &body, nil)
+}
+
+// Poll the import status until Listmonk stops importing. Reports the number of
+// imported subscribers to opts.Progress.
+func (c *APIClient) waitForBulkImport(ctx context.Context, opts ImportOptions) (*BulkImportStatus, error) {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := c.getBulkImportStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(ImportProgress{Processed: status.Imported})
+		}
+		if status.Status != "importing" && status.Status != "stopping" {
+			c.logInfo(ctx, "Listmonk import stopped.", "status", status.Status, "imported", status.Imported, "total", status.Total)
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,14 +3,18 @@ package api
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/exp/maps"
@@ -28,12 +32,41 @@ var subscriptionMap = map[string]string{
 	"PCEngines_seabios":     "network",
 }
 
+// Subject of transactional templates with DPP credentials
+const txTemplateSubject = "DPP credentials"
+
 type APIClient struct {
 	BaseURL        string
 	Username       *string
 	Password       *string
 	Client         *listmonk.Client
 	MailingListIDs sync.Map
+
+	httpClient      *http.Client
+	timeout         time.Duration
+	logger          *slog.Logger
+	lazyLists       bool
+	autoCreateLists bool
+	listsLoaded     atomic.Bool
+	listsMutex      sync.Mutex
+
+	txTemplates     sync.Map
+	txTemplateMutex sync.Mutex
+
+	// Writes are recorded here instead of being sent if not nil
+	plan *Plan
+
+	ledger DeliveryLedger
+
+	sender              Sender
+	subscriptionSenders map[string]Sender
+
+	reviewers       []string
+	requireTestSend bool
+
+	emailPolicy           EmailPolicy
+	disposableDomainsFile string
+	disposableDomains     map[string]bool
 }
 
 type SubscriberInput struct {
@@ -44,7 +77,7 @@ type SubscriberInput struct {
 }
 
 func GetSubscriptionTypes() []string {
-  return maps.Keys(subscriptionMap)
+	return maps.Keys(subscriptionMap)
 }
 
 func mapping[T, U any](ts []T, f func(T) U) []U {
@@ -55,24 +88,96 @@ func mapping[T, U any](ts []T, f func(T) U) []U {
 	return us
 }
 
+// Create a new APIClient. Panics if mailing lists cannot be fetched.
 func NewAPIClient(baseURL string, username, password *string) *APIClient {
+	client, err := NewClient(baseURL, username, password)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// Create a new APIClient configured with options. Unless WithLazyListLoading
+// is used, mailing lists are fetched immediately and an error is returned if
+// that fails.
+func NewClient(baseURL string, username, password *string, opts ...Option) (*APIClient, error) {
 	client := &APIClient{
 		BaseURL:  baseURL,
 		Username: username,
 		Password: password,
-		Client:   listmonk.NewClient(baseURL, username, password),
 	}
 
-	err := client.setListIDs()
+	client.sender = defaultSender
+	for _, opt := range opts {
+		opt(client)
+	}
+	err := client.sender.validate()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return client
+	for subscriptionType, sender := range client.subscriptionSenders {
+		err = client.sender.with(sender).validate()
+		if err != nil {
+			return nil, fmt.Errorf("sender of %s: %w", subscriptionType, err)
+		}
+	}
+
+	if client.httpClient == nil {
+		client.httpClient = &http.Client{}
+	}
+	if client.timeout > 0 {
+		httpClient := *client.httpClient
+		httpClient.Timeout = client.timeout
+		client.httpClient = &httpClient
+	}
+	if client.logger == nil {
+		client.logger = slog.New(NewConsoleHandler(os.Stdout, nil))
+	}
+	client.Client = listmonk.NewClientWithCustomHTTPClient(baseURL, username, password, client.httpClient)
+
+	if client.disposableDomainsFile != "" {
+		domains, err := loadDomainList(client.disposableDomainsFile)
+		if err != nil {
+			return nil, err
+		}
+		client.disposableDomains = domains
+	}
+
+	if client.lazyLists {
+		return client, nil
+	}
+
+	err = client.loadListIDs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Load mailing lists into the MailingListIDs cache unless already loaded
+func (c *APIClient) loadListIDs(ctx context.Context) error {
+	if c.listsLoaded.Load() {
+		return nil
+	}
+
+	c.listsMutex.Lock()
+	defer c.listsMutex.Unlock()
+	if c.listsLoaded.Load() {
+		return nil
+	}
+
+	err := c.setListIDs(ctx)
+	if err != nil {
+		return err
+	}
+	c.listsLoaded.Store(true)
+	return nil
 }
 
-func (c *APIClient) setListIDs() error {
+func (c *APIClient) setListIDs(ctx context.Context) error {
 	getListsService := c.Client.NewGetListsService()
-	lists, err := getListsService.Do(context.Background())
+	getListsService.PerPage("all")
+	lists, err := apiResult(getListsService.Do(ctx))
 	if err != nil {
 		return err
 	}
@@ -85,10 +190,14 @@ func (c *APIClient) setListIDs() error {
 }
 
 // Create a new list and update sync.Map
-func (c *APIClient) createList(name string) (*listmonk.List, error) {
+func (c *APIClient) createList(ctx context.Context, name string) (*listmonk.List, error) {
+	if c.dryRun(ctx, "Create list "+name, http.MethodPost, "/lists", map[string]interface{}{"name": name}) {
+		return &listmonk.List{Name: name}, nil
+	}
+
 	createListService := c.Client.NewCreateListService()
 	createListService.Name(name)
-	list, err := createListService.Do(context.Background())
+	list, err := apiResult(createListService.Do(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -97,407 +206,837 @@ func (c *APIClient) createList(name string) (*listmonk.List, error) {
 	return list, nil
 }
 
-func (c *APIClient) getListID(name string) (uint, error) {
+func (c *APIClient) getListID(ctx context.Context, name string) (uint, error) {
+	err := c.loadListIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	if val, ok := c.MailingListIDs.Load(name); ok {
 		return val.(uint), nil
 	}
-	return 0, fmt.Errorf("list not found: %s", name)
+	return 0, fmt.Errorf("%w: %s", ErrListNotFound, name)
 }
 
 // Create a new subscriber and add them to mailing lists with specified names, including attributes
 func (c *APIClient) CreateSubscriber(name string, email string, lists []string, attrs map[string]interface{}) (uint, error) {
-	listIDs := mapping(lists, func(listName string) uint {
-		id, err := c.getListID(listName)
+	return c.CreateSubscriberContext(context.Background(), name, email, lists, attrs)
+}
+
+// CreateSubscriberContext is like CreateSubscriber but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateSubscriberContext(ctx context.Context, name string, email string, lists []string, attrs map[string]interface{}) (uint, error) {
+	listIDs, err := c.resolveListIDs(ctx, lists)
+	if err != nil {
+		return 0, err
+	}
+	return c.CreateSubscriberListIDsContext(ctx, name, email, listIDs, attrs)
+}
+
+// Get IDs of lists with given names. Missing lists are created if the client
+// was configured with WithAutoCreateLists, otherwise an *UnknownListsError
+// naming all of them is returned.
+func (c *APIClient) resolveListIDs(ctx context.Context, names []string) ([]uint, error) {
+	listIDs := make([]uint, len(names))
+	var unknown []string
+	for i, name := range names {
+		id, err := c.getListID(ctx, name)
+		if errors.Is(err, ErrListNotFound) {
+			unknown = append(unknown, name)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		listIDs[i] = id
+	}
+
+	if len(unknown) == 0 {
+		return listIDs, nil
+	}
+	if !c.autoCreateLists {
+		return nil, &UnknownListsError{Names: unknown}
+	}
+
+	// Concurrent imports may try to create the same lists
+	c.listsMutex.Lock()
+	defer c.listsMutex.Unlock()
+	for i, name := range names {
+		if listIDs[i] != 0 {
+			continue
+		}
+		// The list may have been created already if its name is repeated or
+		// by another goroutine
+		if id, err := c.getListID(ctx, name); err == nil {
+			listIDs[i] = id
+			continue
+		}
+		c.logInfo(ctx, "Creating missing list.", "list", name)
+		list, err := c.createList(ctx, name)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		return id
-	})
-	return c.CreateSubscriberListIDs(name, email, listIDs, attrs)
+		listIDs[i] = list.Id
+	}
+	return listIDs, nil
 }
 
 // Create a new subscriber and add them to mailing lists with specified IDs, including attributes
 func (c *APIClient) CreateSubscriberListIDs(name string, email string, lists []uint, attrs map[string]interface{}) (uint, error) {
+	return c.CreateSubscriberListIDsContext(context.Background(), name, email, lists, attrs)
+}
+
+// CreateSubscriberListIDsContext is like CreateSubscriberListIDs but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateSubscriberListIDsContext(ctx context.Context, name string, email string, lists []uint, attrs map[string]interface{}) (uint, error) {
+	start := time.Now()
+	email, err := c.checkEmail(email)
+	if err != nil {
+		return 0, err
+	}
+	if c.dryRun(ctx, "Create subscriber "+email, http.MethodPost, "/subscribers", map[string]interface{}{
+		"email":   email,
+		"name":    name,
+		"status":  "enabled",
+		"lists":   lists,
+		"attribs": attrs,
+	}) {
+		return 0, nil
+	}
+
 	service := c.Client.NewCreateSubscriberService()
 	service.Email(email)
 	service.Name(name)
 	service.ListIds(lists)
 	service.Attributes(attrs) // Set the attributes here
-	LogInfof("Adding subscriber %s to Listmonk.\n", name)
-	subscriber, err := service.Do(context.Background())
+	c.logInfo(ctx, "Adding subscriber to Listmonk.", "name", name, "email", email)
+	subscriber, err := apiResult(service.Do(ctx))
+	if errors.Is(err, ErrConflict) {
+		return 0, fmt.Errorf("%w: %s: %w", ErrDuplicateEmail, email, err)
+	}
 	if err != nil {
 		return 0, err
 	}
-	LogOKln("Success")
+	c.logOK(ctx, start, "Added subscriber.", "email", email, "subscriber_id", subscriber.Id)
 	return subscriber.Id, nil
 }
 
 // Create a new subscriber from JSON data
 func (c *APIClient) CreateSubscriberFromJSON(jsonData []byte) (uint, error) {
+	return c.CreateSubscriberFromJSONContext(context.Background(), jsonData)
+}
+
+// CreateSubscriberFromJSONContext is like CreateSubscriberFromJSON but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateSubscriberFromJSONContext(ctx context.Context, jsonData []byte) (uint, error) {
 	var input SubscriberInput
 	err := json.Unmarshal(jsonData, &input)
 	if err != nil {
 		return 0, err
 	}
-	return c.CreateSubscriber(input.Name, input.Email, input.Lists, input.Attrs)
+	return c.CreateSubscriberContext(ctx, input.Name, input.Email, input.Lists, input.Attrs)
 }
 
-func (c *APIClient) CreateCampaign(name, subject string, lists []uint, content, contentType string) (uint, error) {
-	service := c.Client.NewCreateCampaignService()
+// How UpsertSubscriber combines given attributes with existing ones
+type MergeMode int
+
+const (
+	// Keep existing attributes, overwriting those that are given
+	MergeAttributes MergeMode = iota
+	// Replace all existing attributes with the given ones
+	ReplaceAttributes
+)
+
+// What UpsertSubscriber did with the subscriber
+type UpsertAction string
+
+const (
+	UpsertCreated   UpsertAction = "created"
+	UpsertUpdated   UpsertAction = "updated"
+	UpsertUnchanged UpsertAction = "unchanged"
+)
+
+// Create a subscriber with given email or update the existing one. The
+// subscriber is added to lists (existing memberships are kept) and attributes
+// are merged according to mode. If name is empty, the existing name is kept
+// and new subscribers are named after their email. Returns the subscriber ID
+// and what was done.
+func (c *APIClient) UpsertSubscriber(email, name string, lists []string, attrs map[string]interface{}, mode MergeMode) (uint, UpsertAction, error) {
+	return c.UpsertSubscriberContext(context.Background(), email, name, lists, attrs, mode)
+}
+
+// UpsertSubscriberContext is like UpsertSubscriber but uses ctx for all Listmonk requests.
+func (c *APIClient) UpsertSubscriberContext(ctx context.Context, email, name string, lists []string, attrs map[string]interface{}, mode MergeMode) (uint, UpsertAction, error) {
+	merge := func(existing map[string]interface{}) map[string]interface{} {
+		if mode == ReplaceAttributes {
+			return attrs
+		}
+		merged := make(map[string]interface{}, len(existing)+len(attrs))
+		maps.Copy(merged, existing)
+		maps.Copy(merged, attrs)
+		return merged
+	}
+	return c.upsertSubscriber(ctx, email, name, lists, merge)
+}
+
+// Create or update subscriber using merge to compute new attributes from the
+// existing ones (nil for new subscribers). Uses one request to look the
+// subscriber up and at most one to write it.
+func (c *APIClient) upsertSubscriber(ctx context.Context, email, name string, lists []string, merge func(map[string]interface{}) map[string]interface{}) (uint, UpsertAction, error) {
+	start := time.Now()
+	email, err := c.checkEmail(email)
+	if err != nil {
+		return 0, "", err
+	}
+
+	listIDs, err := c.resolveListIDs(ctx, lists)
+	if err != nil {
+		return 0, "", err
+	}
+
+	subscriber, err := c.getSubscriberByEmail(ctx, email)
+	if errors.Is(err, ErrSubscriberNotFound) {
+		if name == "" {
+			name = email
+		}
+		id, err := c.CreateSubscriberListIDsContext(ctx, name, email, listIDs, merge(nil))
+		if err != nil {
+			return 0, "", err
+		}
+		return id, UpsertCreated, nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	changed := false
+	if name == "" {
+		name = subscriber.Name
+	}
+	if name != subscriber.Name {
+		changed = true
+	}
+
+	subscriberListIDs := mapping(subscriber.Lists, func(l listmonk.SubscriberList) uint { return l.Id })
+	for _, id := range listIDs {
+		if !slices.Contains(subscriberListIDs, id) {
+			subscriberListIDs = append(subscriberListIDs, id)
+			changed = true
+		}
+	}
+
+	newAttrs := merge(subscriber.Attributes)
+	if !changed {
+		// Compare JSON encodings, as numbers fetched from Listmonk are float64
+		oldJSON, err := json.Marshal(subscriber.Attributes)
+		if err != nil {
+			return 0, "", err
+		}
+		newJSON, err := json.Marshal(newAttrs)
+		if err != nil {
+			return 0, "", err
+		}
+		changed = string(oldJSON) != string(newJSON)
+	}
+
+	if !changed {
+		c.logOK(ctx, start, "Subscriber is up to date.", "email", email, "subscriber_id", subscriber.Id)
+		return subscriber.Id, UpsertUnchanged, nil
+	}
+
+	if c.dryRun(ctx, "Update subscriber "+email, http.MethodPut, fmt.Sprintf("/subscribers/%d", subscriber.Id), map[string]interface{}{
+		"email":   subscriber.Email,
+		"name":    name,
+		"status":  subscriber.Status,
+		"lists":   subscriberListIDs,
+		"attribs": newAttrs,
+	}) {
+		return subscriber.Id, UpsertUpdated, nil
+	}
+
+	c.logInfo(ctx, "Updating subscriber.", "email", email, "subscriber_id", subscriber.Id, "lists", lists)
+	service := c.Client.NewUpdateSubscriberService()
+	service.Id(subscriber.Id)
+	service.Email(subscriber.Email)
 	service.Name(name)
-	service.Subject(subject)
-	service.Lists(lists)
-	service.Body(content)
-	service.ContentType(contentType)
-  service.FromEmail("newsletter@3mdeb.com")
-	LogInfof("Creating campaign: %s.\n", name)
-	campaign, err := service.Do(context.Background())
+	service.Status(subscriber.Status)
+	service.ListIds(subscriberListIDs)
+	service.Attributes(newAttrs)
+	_, err = apiResult(service.Do(ctx))
+	if err != nil {
+		return 0, "", err
+	}
+	c.logOK(ctx, start, "Updated subscriber.", "email", email, "subscriber_id", subscriber.Id)
+	return subscriber.Id, UpsertUpdated, nil
+}
+
+// Create a new campaign with content of given type
+func (c *APIClient) CreateCampaign(name, subject string, lists []uint, content, contentType string) (uint, error) {
+	return c.CreateCampaignContext(context.Background(), name, subject, lists, content, contentType)
+}
+
+// CreateCampaignContext is like CreateCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateCampaignContext(ctx context.Context, name, subject string, lists []uint, content, contentType string) (uint, error) {
+	return c.CreateCampaignFromContext(ctx, name, subject, lists, content, contentType, Sender{})
+}
+
+// Create a new campaign with content of given type, sent by sender instead of
+// the client's sender. Empty fields of sender are taken from the client.
+func (c *APIClient) CreateCampaignFrom(name, subject string, lists []uint, content, contentType string, sender Sender) (uint, error) {
+	return c.CreateCampaignFromContext(context.Background(), name, subject, lists, content, contentType, sender)
+}
+
+// CreateCampaignFromContext is like CreateCampaignFrom but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateCampaignFromContext(ctx context.Context, name, subject string, lists []uint, content, contentType string, sender Sender) (uint, error) {
+	return c.createCampaignFrom(ctx, "", name, subject, lists, content, contentType, sender)
+}
+
+// Create a new campaign sent by the sender of subscriptionType (if not empty)
+// overridden by sender
+func (c *APIClient) createCampaignFrom(ctx context.Context, subscriptionType, name, subject string, lists []uint, content, contentType string, sender Sender) (uint, error) {
+	start := time.Now()
+	sender, err := c.resolveSender(subscriptionType, sender)
 	if err != nil {
 		return 0, err
 	}
-	LogOKln("Campaign created.")
+
+	fields := map[string]interface{}{
+		"name":         name,
+		"subject":      subject,
+		"lists":        lists,
+		"type":         "regular",
+		"body":         content,
+		"content_type": contentType,
+		"from_email":   sender.FromEmail,
+		"messenger":    sender.Messenger,
+	}
+	if headers := sender.headers(); headers != nil {
+		fields["headers"] = headers
+	}
+	c.logInfo(ctx, "Creating campaign.", "campaign", name)
+	campaign, err := c.createCampaign(ctx, "Create campaign "+name, fields)
+	if err != nil {
+		return 0, err
+	}
+	// Nothing is created in dry-run mode
+	if campaign.Id != 0 {
+		c.logOK(ctx, start, "Campaign created.", "campaign", name, "campaign_id", campaign.Id)
+	}
 	return campaign.Id, nil
 }
 
+// Create a campaign from fields as expected by Listmonk
+func (c *APIClient) createCampaign(ctx context.Context, description string, fields map[string]interface{}) (*listmonk.Campaign, error) {
+	if c.dryRun(ctx, description, http.MethodPost, "/campaigns", fields) {
+		name, _ := fields["name"].(string)
+		return &listmonk.Campaign{Name: name}, nil
+	}
+
+	var campaign listmonk.Campaign
+	err := c.callAPI(ctx, http.MethodPost, "/campaigns", fields, &campaign)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
 // Create a new campaign with HTML content
 func (c *APIClient) CreateCampaignHTML(name string, subject string, lists []uint, content string) (uint, error) {
-	return c.CreateCampaign(name, subject, lists, content, "html")
+	return c.CreateCampaignHTMLContext(context.Background(), name, subject, lists, content)
+}
+
+// CreateCampaignHTMLContext is like CreateCampaignHTML but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateCampaignHTMLContext(ctx context.Context, name string, subject string, lists []uint, content string) (uint, error) {
+	return c.CreateCampaignContext(ctx, name, subject, lists, content, "html")
 }
 
-func (c *APIClient) deleteCampaign(campaign *listmonk.Campaign) error {
+func (c *APIClient) deleteCampaign(ctx context.Context, campaign *listmonk.Campaign) error {
+	if c.dryRun(ctx, "Delete campaign "+campaign.Name, http.MethodDelete, fmt.Sprintf("/campaigns/%d", campaign.Id), nil) {
+		return nil
+	}
+
 	deleteCampaignService := c.Client.NewDeleteCampaignService()
 	deleteCampaignService.Id(campaign.Id)
-	return deleteCampaignService.Do(context.Background())
+	return apiError(deleteCampaignService.Do(ctx))
 }
 
-// Get users who subscribed after campaign was launched
-func (c *APIClient) getSubscribersAfterLaunch(campaign *listmonk.Campaign) ([]*listmonk.Subscriber, error) {
-	LogInfof("Checking for already-existing incremental campaign.\n")
-	getCampaignsService := c.Client.NewGetCampaignsService()
-	campaigns, err := getCampaignsService.Do(context.Background())
+// Get users who subscribed after campaign or its latest incremental campaign
+// was launched
+func (c *APIClient) getSubscribersAfterLaunch(ctx context.Context, campaign *listmonk.Campaign) ([]*listmonk.Subscriber, error) {
+	c.logInfo(ctx, "Checking for already-existing incremental campaigns.", "campaign_id", campaign.Id)
+	incCampaigns, err := c.getIncCampaigns(ctx, campaign)
 	if err != nil {
 		return nil, err
 	}
+	launchDate := latestSend(campaign, incCampaigns)
 
-	var incCampaign *listmonk.Campaign = nil
-	var incCampaignLaunchDate string
-	for _, camp := range campaigns {
-		if camp.Name == campaign.Name+"_inc" {
-			incCampaign = camp
-			incCampaignLaunchDate = incCampaign.StartedAt.Format("2006-01-02T15:04:05.999999-07:00")
-			c.deleteCampaign(camp)
-			break
-		}
-	}
-
-	m := func(l listmonk.CampaignList) string { return strconv.Itoa(int(l.Id)) }
+	m := func(l listmonk.CampaignList) interface{} { return l.Id }
 	listIDs := mapping(campaign.Lists, m)
 
-	launchDate := campaign.StartedAt.Format("2006-01-02T15:04:05.999999-07:00")
-
-	var query string
-	if incCampaign != nil {
-		query = fmt.Sprintf("id IN (SELECT subscriber_id FROM subscriber_lists WHERE created_at > '%v' AND list_id IN (%s))", incCampaignLaunchDate, strings.Join(listIDs, ","))
-	} else {
-		query = fmt.Sprintf("id IN (SELECT subscriber_id FROM subscriber_lists WHERE created_at >'%v' AND list_id IN (%s))", launchDate, strings.Join(listIDs, ","))
+	query, err := InSubquery(Column("id"), "subscriber_lists", "subscriber_id", And(
+		Gt(Column("created_at"), launchDate),
+		In(Column("list_id"), listIDs...),
+	)).Build()
+	if err != nil {
+		return nil, err
 	}
 
 	getSubscribersService := c.Client.NewGetSubscribersService()
 	getSubscribersService.Query(query)
+	getSubscribersService.PerPage("all")
 
-	LogInfoln("Fetching new subscribers.")
-	return getSubscribersService.Do(context.Background())
+	c.logInfo(ctx, "Fetching new subscribers.", "campaign_id", campaign.Id, "since", launchDate)
+	return apiResult(getSubscribersService.Do(ctx))
 }
 
-func (c *APIClient) addSubscribersToList(subscribers []*listmonk.Subscriber, list *listmonk.List) error {
-	subscribersListsService := c.Client.NewUpdateSubscribersListsService()
-
+func (c *APIClient) addSubscribersToList(ctx context.Context, subscribers []*listmonk.Subscriber, list *listmonk.List) error {
 	m := func(s *listmonk.Subscriber) uint { return s.Id }
 
 	subscriberIDs := mapping(subscribers, m)
+	if c.dryRun(ctx, fmt.Sprintf("Add %d subscribers to list %s", len(subscriberIDs), list.Name), http.MethodPut, "/subscribers/lists", map[string]interface{}{
+		"ids":             subscriberIDs,
+		"action":          "add",
+		"target_list_ids": []uint{list.Id},
+	}) {
+		return nil
+	}
+
+	subscribersListsService := c.Client.NewUpdateSubscribersListsService()
 	subscribersListsService.ListIds([]uint{list.Id})
 	subscribersListsService.Ids(subscriberIDs)
 	subscribersListsService.Action("add")
-	_, err := subscribersListsService.Do(context.Background())
+	_, err := apiResult(subscribersListsService.Do(ctx))
 	return err
 }
 
-// Create incremental campaign from an existing one
-func (c *APIClient) createIncCampaign(campaign *listmonk.Campaign, tempList *listmonk.List) (*listmonk.Campaign, error) {
-	createCampaignService := c.Client.NewCreateCampaignService()
-	createCampaignService.Name(campaign.Name + "_inc")
+// Create incremental campaign from an existing one. Incremental campaigns are
+// numbered ("<name>_inc", "<name>_inc_2", ...) and tagged with the ID of the
+// original campaign, so that earlier ones and their statistics are kept.
+func (c *APIClient) createIncCampaign(ctx context.Context, campaign *listmonk.Campaign, tempList *listmonk.List) (*listmonk.Campaign, error) {
+	incCampaigns, err := c.getIncCampaigns(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	number := 0
+	for _, camp := range incCampaigns {
+		number = max(number, incCampaignNumber(campaign, camp.Name))
+	}
+	name := incCampaignName(campaign, number+1)
+	tags := append(slices.Clone(campaign.Tags), incrementalTag(campaign.Id))
 
-	// Copy fields from original campaign
-	createCampaignService.Subject(campaign.Subject)
-	createCampaignService.Type(campaign.Type)
-	createCampaignService.Body(campaign.Body)
-	createCampaignService.Lists([]uint{tempList.Id})
-	createCampaignService.ContentType(campaign.ContentType)
-	createCampaignService.FromEmail(campaign.FromEmail)
-	createCampaignService.Messenger(campaign.Messenger)
-	createCampaignService.TemplateId(campaign.TemplateId)
-	createCampaignService.Tags(campaign.Tags)
+	c.logInfo(ctx, "Creating incremental campaign.", "campaign_id", campaign.Id, "list", tempList.Name)
+	return c.copyCampaign(ctx, "Create incremental campaign "+name, campaign, name, []uint{tempList.Id}, tags)
+}
+
+// Create a draft campaign with the content and settings of campaign, but with
+// a different name, lists and tags
+func (c *APIClient) copyCampaign(ctx context.Context, description string, campaign *listmonk.Campaign, name string, lists []uint, tags []string) (*listmonk.Campaign, error) {
+	headers, err := c.getCampaignHeaders(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
 
-	LogInfoln("Creating incremental campaign.")
-	return createCampaignService.Do(context.Background())
+	// Copy fields from original campaign
+	fields := campaignFields(campaign)
+	fields["name"] = name
+	fields["lists"] = lists
+	fields["tags"] = tags
+	fields["headers"] = headers
+	fields["send_later"] = false
+	fields["send_at"] = nil
+	return c.createCampaign(ctx, description, fields)
 }
 
-// Launch campaign or send finished campaign to newly subscribed users
-func (c *APIClient) LaunchCampaign(id uint) (bool, error) {
-	// Fetch campaign launch date and mailing lists
+// Get campaign by ID
+func (c *APIClient) getCampaign(ctx context.Context, id uint) (*listmonk.Campaign, error) {
 	getCampaignService := c.Client.NewGetCampaignService()
 	getCampaignService.Id(id)
+	campaign, err := apiResult(getCampaignService.Do(ctx))
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %d: %w", ErrCampaignNotFound, id, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// Change status of a campaign, e.g. to "running" to launch it
+func (c *APIClient) setCampaignStatus(ctx context.Context, campaign *listmonk.Campaign, status string) error {
+	if c.dryRun(ctx, fmt.Sprintf("Set status of campaign %s to %s", campaign.Name, status), http.MethodPut, fmt.Sprintf("/campaigns/%d/status", campaign.Id), map[string]interface{}{"status": status}) {
+		return nil
+	}
+
+	updateCampaignStatusService := c.Client.NewUpdateCampaignStatusService()
+	updateCampaignStatusService.Id(campaign.Id)
+	updateCampaignStatusService.Status(status)
+	_, err := apiResult(updateCampaignStatusService.Do(ctx))
+	return err
+}
+
+// Update a campaign. Listmonk replaces all fields on update, so the current
+// fields of campaign are sent with changes applied on top of them.
+func (c *APIClient) updateCampaign(ctx context.Context, campaign *listmonk.Campaign, changes map[string]interface{}) (*listmonk.Campaign, error) {
+	headers, err := c.getCampaignHeaders(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	body := campaignFields(campaign)
+	body["headers"] = headers
+	for key, value := range changes {
+		body[key] = value
+	}
+
+	endpoint := fmt.Sprintf("/campaigns/%d", campaign.Id)
+	if c.dryRun(ctx, "Update campaign "+campaign.Name, http.MethodPut, endpoint, body) {
+		return campaign, nil
+	}
+
+	var updated listmonk.Campaign
+	err = c.callAPI(ctx, http.MethodPut, endpoint, body, &updated)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
 
-	LogInfoln("Fetching campaign data.")
+// Fields of a campaign as expected by Listmonk's create and update requests
+func campaignFields(campaign *listmonk.Campaign) map[string]interface{} {
+	fields := map[string]interface{}{
+		"name":         campaign.Name,
+		"subject":      campaign.Subject,
+		"lists":        mapping(campaign.Lists, func(l listmonk.CampaignList) uint { return l.Id }),
+		"from_email":   campaign.FromEmail,
+		"type":         campaign.Type,
+		"content_type": campaign.ContentType,
+		"body":         campaign.Body,
+		"messenger":    campaign.Messenger,
+		"template_id":  campaign.TemplateId,
+		"tags":         campaign.Tags,
+		"send_later":   !campaign.SendAt.IsZero(),
+		"send_at":      nil,
+	}
+	if !campaign.SendAt.IsZero() {
+		fields["send_at"] = campaign.SendAt
+	}
+	return fields
+}
+
+// Launch campaign or send finished campaign to newly subscribed users. With
+// WithDeliveryLedger, the finished campaign is sent to all its recipients who
+// have not received it yet according to the ledger.
+func (c *APIClient) LaunchCampaign(id uint) (bool, error) {
+	return c.LaunchCampaignContext(context.Background(), id)
+}
 
-	campaign, err := getCampaignService.Do(context.Background())
+// LaunchCampaignContext is like LaunchCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) LaunchCampaignContext(ctx context.Context, id uint) (bool, error) {
+	start := time.Now()
+	// Fetch campaign launch date and mailing lists
+	c.logInfo(ctx, "Fetching campaign data.", "campaign_id", id)
+	campaign, err := c.getCampaign(ctx, id)
 	if err != nil {
 		return false, err
 	}
 
 	if len(campaign.Lists) == 0 {
-		LogWarningln("The campaign targets no mailing lists! Aborting.")
+		c.logWarning(ctx, start, "The campaign targets no mailing lists! Aborting.", "campaign_id", id)
 		return false, nil
 	}
 
 	// If campaign has never been launched - launch it
 	if campaign.StartedAt.IsZero() {
-		updateCampaignStatusService := c.Client.NewUpdateCampaignStatusService()
-		updateCampaignStatusService.Id(id)
-		updateCampaignStatusService.Status("running")
+		if c.requireTestSend {
+			err = c.checkTestSent(ctx, campaign)
+			if err != nil {
+				return false, err
+			}
+		}
 
-		LogInfoln("The campaign has not been launched before. Launching now.")
-		_, err := updateCampaignStatusService.Do(context.Background())
+		c.logInfo(ctx, "The campaign has not been launched before. Launching now.", "campaign_id", id)
+		err := c.setCampaignStatus(ctx, campaign, "running")
+		if err != nil {
+			return false, err
+		}
+		err = c.recordLaunch(ctx, campaign)
 		if err != nil {
 			return false, err
 		}
-		LogOKf("Successfully launched campaign %s.\n", campaign.Name)
+		c.logOK(ctx, start, "Successfully launched campaign.", "campaign", campaign.Name, "campaign_id", id)
 		return true, nil
 	}
 
-	subscribers, err := c.getSubscribersAfterLaunch(campaign)
+	c.cleanupOrphanedTempLists(ctx)
+
+	var subscribers []*listmonk.Subscriber
+	if c.ledger != nil {
+		subscribers, err = c.getUndeliveredSubscribers(ctx, campaign)
+	} else {
+		subscribers, err = c.getSubscribersAfterLaunch(ctx, campaign)
+	}
 	if err != nil {
 		return false, err
 	}
 
 	if len(subscribers) == 0 {
-		LogWarningln("No new subscribers since last launch! Aborting.")
+		c.logWarning(ctx, start, "No new subscribers since last launch! Aborting.", "campaign_id", id)
 		return false, nil
 	}
 
-	// Create temporary list
-	tempList, err := c.createList("temp_list")
+	err = c.sendIncCampaign(ctx, campaign, subscribers)
 	if err != nil {
 		return false, err
 	}
-
-	// Add subscribers to temporary list
-	err = c.addSubscribersToList(subscribers, tempList)
+	err = c.recordDelivery(ctx, campaign, subscribers)
 	if err != nil {
 		return false, err
 	}
 
-	incCampaign, err := c.createIncCampaign(campaign, tempList)
+	c.logOK(ctx, start, "Successfully resumed campaign.", "campaign", campaign.Name, "campaign_id", id, "subscribers", len(subscribers))
+	return true, nil
+}
+
+// Send an incremental campaign to subscribers through a temporary list.
+// Listmonk reads the recipients from the list while sending, so the list is
+// kept once the campaign is launched and removed by cleanupOrphanedTempLists
+// after the campaign is finished. It is removed right away if the campaign
+// could not be launched.
+func (c *APIClient) sendIncCampaign(ctx context.Context, campaign *listmonk.Campaign, subscribers []*listmonk.Subscriber) (err error) {
+	start := time.Now()
+	// Create temporary list
+	tempList, err := c.createList(ctx, tempListName(campaign))
 	if err != nil {
-		return false, err
+		return err
 	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Clean up even if ctx was cancelled
+		cleanupErr := c.deleteListID(context.WithoutCancel(ctx), tempList.Id, tempList.Name)
+		if cleanupErr != nil {
+			c.logWarning(ctx, start, "Could not delete temporary list.", "list", tempList.Name, "error", cleanupErr)
+		}
+	}()
 
-	// Launch incremental campaign
-	updateCampaignStatusService := c.Client.NewUpdateCampaignStatusService()
-	updateCampaignStatusService.Id(incCampaign.Id)
-	updateCampaignStatusService.Status("running")
-
-	LogInfoln("Launching incremental campaign.")
-	_, err = updateCampaignStatusService.Do(context.Background())
+	// Add subscribers to temporary list
+	err = c.addSubscribersToList(ctx, subscribers, tempList)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	// Remove temporary list
-	deleteListService := c.Client.NewDeleteListService()
-	deleteListService.Id(tempList.Id)
-	err = deleteListService.Do(context.Background())
+	incCampaign, err := c.createIncCampaign(ctx, campaign, tempList)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	LogOKf("Successfully resumed campaign %s.\n", campaign.Name)
-	return true, nil
+	// Launch incremental campaign
+	c.logInfo(ctx, "Launching incremental campaign.", "campaign_id", campaign.Id, "incremental_campaign_id", incCampaign.Id, "subscribers", len(subscribers))
+	return c.setCampaignStatus(ctx, incCampaign, "running")
 }
 
 // Delete subscriber by ID
 func (c *APIClient) DeleteSubscriberID(id uint) error {
+	return c.DeleteSubscriberIDContext(context.Background(), id)
+}
+
+// DeleteSubscriberIDContext is like DeleteSubscriberID but uses ctx for all Listmonk requests.
+func (c *APIClient) DeleteSubscriberIDContext(ctx context.Context, id uint) error {
+	start := time.Now()
+	if c.dryRun(ctx, fmt.Sprintf("Delete subscriber %d", id), http.MethodDelete, fmt.Sprintf("/subscribers/%d", id), nil) {
+		return nil
+	}
+
 	deleteSubscriberService := c.Client.NewDeleteSubscriberService()
 	deleteSubscriberService.Id(id)
-	_, err := deleteSubscriberService.Do(context.Background())
-	LogOKln("Successfully deleted subscriber.")
-	return err
+	_, err := apiResult(deleteSubscriberService.Do(ctx))
+	if err != nil {
+		return err
+	}
+	c.logOK(ctx, start, "Successfully deleted subscriber.", "subscriber_id", id)
+	return nil
 }
 
 // Get ID of subscriber with given email
-func (c *APIClient) getSubscriberID(email string) (uint, error) {
-	getSubscribersService := c.Client.NewGetSubscribersService()
-	getSubscribersService.Query(fmt.Sprintf("subscribers.email = '%s'", email))
-	subscribers, err := getSubscribersService.Do(context.Background())
+func (c *APIClient) getSubscriberID(ctx context.Context, email string) (uint, error) {
+	subscriber, err := c.getSubscriberByEmail(ctx, email)
 	if err != nil {
 		return 0, err
 	}
+	return subscriber.Id, nil
+}
+
+// Get subscriber with given email, including lists and attributes. Emails are
+// compared case-insensitively; if several subscribers match, the one with the
+// exact email is returned.
+func (c *APIClient) getSubscriberByEmail(ctx context.Context, email string) (*listmonk.Subscriber, error) {
+	query, err := Eq(Lower(Column("subscribers.email")), lookupEmail(email)).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	getSubscribersService := c.Client.NewGetSubscribersService()
+	getSubscribersService.Query(query)
+	subscribers, err := apiResult(getSubscribersService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
 
 	if len(subscribers) == 0 {
-		return 0, fmt.Errorf("Could not find subscriber with email %s", email)
+		return nil, fmt.Errorf("%w with email %s", ErrSubscriberNotFound, email)
 	}
 	if len(subscribers) > 1 {
-		return 0, fmt.Errorf("Query returned too many results")
+		for _, subscriber := range subscribers {
+			if subscriber.Email == strings.TrimSpace(email) {
+				return subscriber, nil
+			}
+		}
+		return nil, fmt.Errorf("%w for email %s", ErrAmbiguousSubscriber, email)
 	}
-	return subscribers[0].Id, nil
+	return subscribers[0], nil
 }
 
 // Delete subscriber by email
 func (c *APIClient) DeleteSubscriberEmail(email string) error {
-	LogInfof("Deleting subscriber %s from Listmonk.\n", email)
-	subscriberID, err := c.getSubscriberID(email)
+	return c.DeleteSubscriberEmailContext(context.Background(), email)
+}
+
+// DeleteSubscriberEmailContext is like DeleteSubscriberEmail but uses ctx for all Listmonk requests.
+func (c *APIClient) DeleteSubscriberEmailContext(ctx context.Context, email string) error {
+	c.logInfo(ctx, "Deleting subscriber from Listmonk.", "email", email)
+	subscriberID, err := c.getSubscriberID(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	return c.DeleteSubscriberID(subscriberID)
+	return c.DeleteSubscriberIDContext(ctx, subscriberID)
 }
 
 // Add subscribers from CSV file.
 // Assumes CSV has columns: Duration (years), Email, Date received, Expiration date
+// in any order, see DefaultCSVMapping.
 func (c *APIClient) AddSubscribersFromCSV(path, list string, passwords map[string]string) error {
-	LogInfoln("Adding subscribers from CSV to Listmonk.")
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return err
-	}
-
-	if len(records) < 1 {
-		return fmt.Errorf("no records found")
-	}
-
-	// Skip the header row
-	records = records[1:]
-
-	for _, record := range records {
-		if len(record) < 4 {
-			return fmt.Errorf("invalid record length: %v", record)
-		}
+	return c.AddSubscribersFromCSVContext(context.Background(), path, list, passwords)
+}
 
-		duration := record[0]
-		email := record[1]
-		received := record[2]
-		expiration := record[3]
-		attrs := map[string]interface{}{
-			"key": passwords[email],
-			fmt.Sprintf("duration_%s", strings.ToLower(list)):        duration,
-			fmt.Sprintf("created_%s", strings.ToLower(list)):         received,
-			fmt.Sprintf("expiration_date_%s", strings.ToLower(list)): expiration,
-		}
+// AddSubscribersFromCSVContext is like AddSubscribersFromCSV but uses ctx for all Listmonk requests.
+func (c *APIClient) AddSubscribersFromCSVContext(ctx context.Context, path, list string, passwords map[string]string) error {
+	_, err := c.AddSubscribersFromCSVWithOptionsContext(ctx, path, list, passwords, ImportOptions{})
+	return err
+}
 
-		// If subscriber does not already exists
-		if _, err := c.getSubscriberID(email); err != nil {
-			LogInfof("Subscriber %s does not exist in Listmonk. Adding now.\n", email)
-			_, err = c.CreateSubscriber(email, email, []string{list}, attrs)
-			if err != nil {
-				return err
-			}
-			LogOKf("Added subscriber %s.\n", email)
-			continue
+// Merge function for a new subscription: subscription attributes are
+// overwritten, but the key of an existing subscriber is never changed
+func subscriptionMerge(attrs map[string]interface{}, key string) func(map[string]interface{}) map[string]interface{} {
+	return func(existing map[string]interface{}) map[string]interface{} {
+		merged := make(map[string]interface{}, len(existing)+len(attrs)+1)
+		maps.Copy(merged, existing)
+		maps.Copy(merged, attrs)
+		if _, ok := existing["key"]; !ok {
+			merged["key"] = key
 		}
-		err := c.AddToList(email, list)
-		if err != nil {
-			return err
-		}
-		LogInfof("Adding new subscription for subscriber %s.\n", email)
-		err = c.SetAttribute(email, fmt.Sprintf("duration_%s", strings.ToLower(list)), duration)
-		if err != nil {
-			return err
-		}
-		err = c.SetAttribute(email, fmt.Sprintf("created_%s", strings.ToLower(list)), received)
-		if err != nil {
-			return err
-		}
-		err = c.SetAttribute(email, fmt.Sprintf("expiration_date_%s", strings.ToLower(list)), expiration)
-		if err != nil {
-			return err
-		}
-		LogOKln("Success.")
+		return merged
 	}
-	return nil
 }
 
-// Create campaign from HTML on a list given by name.
+// Create campaign from HTML on a list given by name. The campaign is sent by
+// the sender configured for the list name with WithSubscriptionSender, if any.
 func (c *APIClient) CreateCampaignHTMLOnListName(campaignName string, subject string, listName string, content string) (uint, error) {
+	return c.CreateCampaignHTMLOnListNameContext(context.Background(), campaignName, subject, listName, content)
+}
+
+// CreateCampaignHTMLOnListNameContext is like CreateCampaignHTMLOnListName but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateCampaignHTMLOnListNameContext(ctx context.Context, campaignName string, subject string, listName string, content string) (uint, error) {
 	getListsService := c.Client.NewGetListsService()
-	lists, err := getListsService.Do(context.Background())
+	getListsService.PerPage("all")
+	lists, err := apiResult(getListsService.Do(ctx))
 	if err != nil {
 		return 0, err
 	}
 
 	for _, list := range lists {
 		if list.Name == listName {
-			return c.CreateCampaignHTML(campaignName, subject, []uint{list.Id}, content)
+			return c.createCampaignFrom(ctx, listName, campaignName, subject, []uint{list.Id}, content, "html", Sender{})
 		}
 	}
-	return 0, fmt.Errorf("Could not find list %s", listName)
+	return 0, fmt.Errorf("%w: %s", ErrListNotFound, listName)
 }
 
 // Modify subscriber list memberships.
-func (c *APIClient) updateSubscriberLists(email string, listNames []string, action string) error {
-	subscriberID, err := c.getSubscriberID(email)
+func (c *APIClient) updateSubscriberLists(ctx context.Context, email string, listNames []string, action string) error {
+	start := time.Now()
+	subscriberID, err := c.getSubscriberID(ctx, email)
 	if err != nil {
 		return err
 	}
 
-	subscriber, err := c.GetSubscriber(subscriberID)
+	subscriber, err := c.GetSubscriberContext(ctx, subscriberID)
+	if err != nil {
+		return err
+	}
 	if len(subscriber.Lists) == 1 && action == "remove" {
-		return c.DeleteSubscriberID(subscriberID)
+		return c.DeleteSubscriberIDContext(ctx, subscriberID)
 	}
 
 	listIDs := make([]uint, len(listNames))
 	for i, listName := range listNames {
-		listID, err := c.getListID(listName)
+		listID, err := c.getListID(ctx, listName)
 		if err != nil {
 			return err
 		}
 		listIDs[i] = listID
 	}
 
+	if c.dryRun(ctx, fmt.Sprintf("Update lists of subscriber %s (%s)", email, action), http.MethodPut, "/subscribers/lists", map[string]interface{}{
+		"ids":             []uint{subscriberID},
+		"action":          action,
+		"target_list_ids": listIDs,
+	}) {
+		return nil
+	}
+
 	subscribersListsService := c.Client.NewUpdateSubscribersListsService()
 	subscribersListsService.Ids([]uint{subscriberID})
 	subscribersListsService.ListIds(listIDs)
 	subscribersListsService.Action(action)
-	_, err = subscribersListsService.Do(context.Background())
+	_, err = apiResult(subscribersListsService.Do(ctx))
 	if err == nil {
-		LogOKln("Success")
+		c.logOK(ctx, start, "Updated subscriber lists.", "email", email, "lists", listNames, "action", action)
 	}
 	return err
 }
 
 // Remove subscriber from a list. Deletes subscriber if removed from all lists.
 func (c *APIClient) RemoveFromList(email string, listName string) error {
-	LogInfof("Removing subscriber %s from list %s.\n", email, listName)
-	return c.updateSubscriberLists(email, []string{listName}, "remove")
+	return c.RemoveFromListContext(context.Background(), email, listName)
+}
+
+// RemoveFromListContext is like RemoveFromList but uses ctx for all Listmonk requests.
+func (c *APIClient) RemoveFromListContext(ctx context.Context, email string, listName string) error {
+	c.logInfo(ctx, "Removing subscriber from list.", "email", email, "list", listName)
+	return c.updateSubscriberLists(ctx, email, []string{listName}, "remove")
 }
 
 // Add subscriber to list
 func (c *APIClient) AddToList(email string, listName string) error {
-	LogInfof("Adding subscriber %s to list %s.\n", email, listName)
-	return c.updateSubscriberLists(email, []string{listName}, "add")
+	return c.AddToListContext(context.Background(), email, listName)
 }
 
-// Launch campaign on list
+// AddToListContext is like AddToList but uses ctx for all Listmonk requests.
+func (c *APIClient) AddToListContext(ctx context.Context, email string, listName string) error {
+	c.logInfo(ctx, "Adding subscriber to list.", "email", email, "list", listName)
+	return c.updateSubscriberLists(ctx, email, []string{listName}, "add")
+}
+
+// Launch campaign on list. A draft campaign still using the client's sender
+// is switched to the sender configured for the list name with
+// WithSubscriptionSender, if any, before it is launched.
 func (c *APIClient) LaunchCampaignListName(listName string) (bool, error) {
+	return c.LaunchCampaignListNameContext(context.Background(), listName)
+}
+
+// LaunchCampaignListNameContext is like LaunchCampaignListName but uses ctx for all Listmonk requests.
+func (c *APIClient) LaunchCampaignListNameContext(ctx context.Context, listName string) (bool, error) {
 	getCampaignsService := c.Client.NewGetCampaignsService()
-	campaigns, err := getCampaignsService.Do(context.Background())
+	getCampaignsService.PerPage("all")
+	campaigns, err := apiResult(getCampaignsService.Do(ctx))
 	if err != nil {
 		return false, err
 	}
@@ -505,39 +1044,61 @@ func (c *APIClient) LaunchCampaignListName(listName string) (bool, error) {
 	for _, campaign := range campaigns {
 		for _, list := range campaign.Lists {
 			if list.Name == listName {
-				return c.LaunchCampaign(campaign.Id)
+				_, err = c.applySubscriptionSender(ctx, campaign, listName)
+				if err != nil {
+					return false, err
+				}
+				return c.LaunchCampaignContext(ctx, campaign.Id)
 			}
 		}
 	}
-	return false, fmt.Errorf("Could not find list %s", listName)
+	return false, fmt.Errorf("%w: no campaign targets list %s", ErrCampaignNotFound, listName)
 }
 
 // Add subscriber to list and launch campaign
 func (c *APIClient) AddAndSendCampaign(email string, listName string) (bool, error) {
-	err := c.AddToList(email, listName)
+	return c.AddAndSendCampaignContext(context.Background(), email, listName)
+}
+
+// AddAndSendCampaignContext is like AddAndSendCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) AddAndSendCampaignContext(ctx context.Context, email string, listName string) (bool, error) {
+	err := c.AddToListContext(ctx, email, listName)
 	if err != nil {
 		return false, err
 	}
 
-	return c.LaunchCampaignListName(listName)
+	return c.LaunchCampaignListNameContext(ctx, listName)
 }
 
 // Add subscribers from CSV and launch campaigns of affected lists. Return true
 // if all campaigns were launched successfully
 func (c *APIClient) AddCSVAndSendCampaign(path, list string, passwords map[string]string) (bool, error) {
-	err := c.AddSubscribersFromCSV(path, list, passwords)
+	return c.AddCSVAndSendCampaignContext(context.Background(), path, list, passwords)
+}
+
+// AddCSVAndSendCampaignContext is like AddCSVAndSendCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) AddCSVAndSendCampaignContext(ctx context.Context, path, list string, passwords map[string]string) (bool, error) {
+	err := c.AddSubscribersFromCSVContext(ctx, path, list, passwords)
 	if err != nil {
 		return false, err
 	}
 
-	return c.LaunchCampaignListName(list)
+	return c.LaunchCampaignListNameContext(ctx, list)
 }
 
 // GetSubscriber retrieves a subscriber by ID
 func (c *APIClient) GetSubscriber(subscriberID uint) (*listmonk.Subscriber, error) {
+	return c.GetSubscriberContext(context.Background(), subscriberID)
+}
+
+// GetSubscriberContext is like GetSubscriber but uses ctx for all Listmonk requests.
+func (c *APIClient) GetSubscriberContext(ctx context.Context, subscriberID uint) (*listmonk.Subscriber, error) {
 	service := c.Client.NewGetSubscriberService()
 	service.Id(subscriberID)
-	subscriber, err := service.Do(context.Background())
+	subscriber, err := apiResult(service.Do(ctx))
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %d: %w", ErrSubscriberNotFound, subscriberID, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -546,67 +1107,116 @@ func (c *APIClient) GetSubscriber(subscriberID uint) (*listmonk.Subscriber, erro
 
 // GetSubscriberAttributes retrieves a subscriber's attributes by ID
 func (c *APIClient) GetSubscriberAttributes(subscriberID uint) (map[string]interface{}, error) {
-	subscriber, err := c.GetSubscriber(subscriberID)
+	return c.GetSubscriberAttributesContext(context.Background(), subscriberID)
+}
+
+// GetSubscriberAttributesContext is like GetSubscriberAttributes but uses ctx for all Listmonk requests.
+func (c *APIClient) GetSubscriberAttributesContext(ctx context.Context, subscriberID uint) (map[string]interface{}, error) {
+	subscriber, err := c.GetSubscriberContext(ctx, subscriberID)
 	if err != nil {
 		return nil, err
 	}
 	return subscriber.Attributes, nil
 }
 
+// Retrieve a subscriber's attributes by email
 func (c *APIClient) GetSubscriberAttributesEmail(email string) (map[string]interface{}, error) {
-	subscriberID, err := c.getSubscriberID(email)
+	return c.GetSubscriberAttributesEmailContext(context.Background(), email)
+}
+
+// GetSubscriberAttributesEmailContext is like GetSubscriberAttributesEmail but uses ctx for all Listmonk requests.
+func (c *APIClient) GetSubscriberAttributesEmailContext(ctx context.Context, email string) (map[string]interface{}, error) {
+	subscriberID, err := c.getSubscriberID(ctx, email)
 	if err != nil {
 		return nil, err
 	}
-	return c.GetSubscriberAttributes(subscriberID)
+	return c.GetSubscriberAttributesContext(ctx, subscriberID)
 }
 
 // UpdateSubscriberAttributes updates a subscriber's attributes
 func (c *APIClient) UpdateSubscriberAttributes(subscriberID uint, attrs map[string]interface{}) error {
+	return c.UpdateSubscriberAttributesContext(context.Background(), subscriberID, attrs)
+}
+
+// UpdateSubscriberAttributesContext is like UpdateSubscriberAttributes but uses ctx for all Listmonk requests.
+func (c *APIClient) UpdateSubscriberAttributesContext(ctx context.Context, subscriberID uint, attrs map[string]interface{}) error {
 	// Get the current subscriber details
-	subscriber, err := c.GetSubscriber(subscriberID)
+	subscriber, err := c.GetSubscriberContext(ctx, subscriberID)
 	if err != nil {
 		return err
 	}
 
+	// Extract list IDs from subscriber's lists
+	listIDs := mapping(subscriber.Lists, func(l listmonk.SubscriberList) uint { return l.Id })
+	if c.dryRun(ctx, "Update attributes of subscriber "+subscriber.Email, http.MethodPut, fmt.Sprintf("/subscribers/%d", subscriberID), map[string]interface{}{
+		"email":   subscriber.Email,
+		"name":    subscriber.Name,
+		"status":  subscriber.Status,
+		"lists":   listIDs,
+		"attribs": attrs,
+	}) {
+		return nil
+	}
+
 	service := c.Client.NewUpdateSubscriberService()
 	service.Id(subscriberID)
 	service.Email(subscriber.Email) // Set the email
 	service.Name(subscriber.Name)   // Set the name
 	service.Status(subscriber.Status)
-	// Extract list IDs from subscriber's lists
-	listIDs := mapping(subscriber.Lists, func(l listmonk.SubscriberList) uint { return l.Id })
 	service.ListIds(listIDs)
 	service.Attributes(attrs) // Use Attribs instead of Attrs
 
-	_, err = service.Do(context.Background())
+	_, err = apiResult(service.Do(ctx))
 	return err
 }
 
+// Update a subscriber's attributes by email
 func (c *APIClient) UpdateSubscriberAttributesEmail(email string, attrs map[string]interface{}) error {
-	subscriberID, err := c.getSubscriberID(email)
+	return c.UpdateSubscriberAttributesEmailContext(context.Background(), email, attrs)
+}
+
+// UpdateSubscriberAttributesEmailContext is like UpdateSubscriberAttributesEmail but uses ctx for all Listmonk requests.
+func (c *APIClient) UpdateSubscriberAttributesEmailContext(ctx context.Context, email string, attrs map[string]interface{}) error {
+	subscriberID, err := c.getSubscriberID(ctx, email)
 	if err != nil {
 		return err
 	}
-	return c.UpdateSubscriberAttributes(subscriberID, attrs)
+	return c.UpdateSubscriberAttributesContext(ctx, subscriberID, attrs)
 }
 
 // Set a single attribute for subscriber
 func (c *APIClient) SetAttribute(email, key, value string) error {
-	attrs, err := c.GetSubscriberAttributesEmail(email)
+	return c.SetAttributeContext(context.Background(), email, key, value)
+}
+
+// SetAttributeContext is like SetAttribute but uses ctx for all Listmonk requests.
+func (c *APIClient) SetAttributeContext(ctx context.Context, email, key, value string) error {
+	attrs, err := c.GetSubscriberAttributesEmailContext(ctx, email)
 	if err != nil {
 		return err
 	}
 	attrs[key] = value
-	return c.UpdateSubscriberAttributesEmail(email, attrs)
+	return c.UpdateSubscriberAttributesEmailContext(ctx, email, attrs)
 }
 
+// List subscribers of a list with their expiration dates
 func (c *APIClient) ListSubscribers(listName string) ([]map[string]string, error) {
-	LogInfof("Fetching subscribers of list %s.\n", listName)
+	return c.ListSubscribersContext(context.Background(), listName)
+}
+
+// ListSubscribersContext is like ListSubscribers but uses ctx for all Listmonk requests.
+func (c *APIClient) ListSubscribersContext(ctx context.Context, listName string) ([]map[string]string, error) {
+	start := time.Now()
+	c.logInfo(ctx, "Fetching subscribers of list.", "list", listName)
 	var result []map[string]string
-	listID, err := c.getListID(listName)
+	listID, err := c.getListID(ctx, listName)
+	if err != nil {
+		return nil, err
+	}
 	getSubscribersService := c.Client.NewGetSubscribersService()
-	subscribers, err := getSubscribersService.Do(context.Background())
+	getSubscribersService.ListIds([]uint{listID})
+	getSubscribersService.PerPage("all")
+	subscribers, err := apiResult(getSubscribersService.Do(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -617,7 +1227,7 @@ func (c *APIClient) ListSubscribers(listName string) ([]map[string]string, error
 			if list.Id == listID {
 				expirationString, ok := subscriber.Attributes[key].(string)
 				if !ok {
-					return nil, fmt.Errorf("expiration_date is not a string")
+					return nil, fmt.Errorf("%w: %s of subscriber %s is not a string", ErrInvalidAttribute, key, subscriber.Email)
 				}
 
 				result = append(result, map[string]string{
@@ -629,36 +1239,57 @@ func (c *APIClient) ListSubscribers(listName string) ([]map[string]string, error
 			}
 		}
 	}
-	LogOKln("Success")
+	c.logOK(ctx, start, "Fetched subscribers of list.", "list", listName, "subscribers", len(result))
 	return result, nil
 }
 
+// Delete a list by name
 func (c *APIClient) DeleteList(name string) error {
-	LogInfof("Deleting list: %s.\n", name)
-	listID, err := c.getListID(name)
+	return c.DeleteListContext(context.Background(), name)
+}
+
+// DeleteListContext is like DeleteList but uses ctx for all Listmonk requests.
+func (c *APIClient) DeleteListContext(ctx context.Context, name string) error {
+	start := time.Now()
+	c.logInfo(ctx, "Deleting list.", "list", name)
+	listID, err := c.getListID(ctx, name)
 	if err != nil {
-		return fmt.Errorf("Could not delete list: %s", name)
+		return fmt.Errorf("Could not delete list: %w", err)
 	}
-	deleteListService := c.Client.NewDeleteListService()
-	deleteListService.Id(listID)
-	err = deleteListService.Do(context.Background())
+	err = c.deleteListID(ctx, listID, name)
 	if err == nil {
-		LogOKln("Success")
+		c.logOK(ctx, start, "Deleted list.", "list", name)
 	}
 	return err
 }
 
+// Delete list with given ID, name is only used for the dry-run plan
+func (c *APIClient) deleteListID(ctx context.Context, id uint, name string) error {
+	if c.dryRun(ctx, "Delete list "+name, http.MethodDelete, fmt.Sprintf("/lists/%d", id), nil) {
+		return nil
+	}
+
+	deleteListService := c.Client.NewDeleteListService()
+	deleteListService.Id(id)
+	err := apiError(deleteListService.Do(ctx))
+	if err != nil {
+		return err
+	}
+	c.MailingListIDs.Delete(name)
+	return nil
+}
+
 func (c *APIClient) formatEmailTemplate(emailType, name, password, expiration_date, config_path string) (string, error) {
 	var filePath string
 	switch emailType {
 	case "desktop":
-    filePath = filepath.Join(config_path, "dpp_desktop")
+		filePath = filepath.Join(config_path, "dpp_desktop")
 	case "laptop":
-    filePath = filepath.Join(config_path, "dpp_laptop")
+		filePath = filepath.Join(config_path, "dpp_laptop")
 	case "network":
-    filePath = filepath.Join(config_path, "dpp_network")
+		filePath = filepath.Join(config_path, "dpp_network")
 	default:
-		return "", fmt.Errorf("Wrong email type! Available types: desktop, laptop, network")
+		return "", fmt.Errorf("%w! Available types: desktop, laptop, network", ErrInvalidTemplateType)
 	}
 
 	content, err := os.ReadFile(filePath)
@@ -673,53 +1304,146 @@ func (c *APIClient) formatEmailTemplate(emailType, name, password, expiration_da
 	return text, nil
 }
 
-func (c *APIClient) SendEmail(subscriptionType, subscriberEmail, name, config_path string) error {
-	LogInfof("Sending email to subscriber %s.\n", subscriberEmail)
-	attrs, err := c.GetSubscriberAttributesEmail(subscriberEmail)
+// Transactional template stored in Listmonk
+type txTemplate struct {
+	id   uint
+	body string
+}
+
+// Get ID of the transactional template for given email type. The template is
+// created from the file in config_path if it does not exist in Listmonk yet,
+// and updated if the file has changed.
+func (c *APIClient) getTxTemplateID(ctx context.Context, emailType, config_path string) (uint, error) {
+	templateName := "dpp_" + emailType
+
+	// Template placeholders are filled in by Listmonk from the "data" field
+	// of the transactional message
+	body, err := c.formatEmailTemplate(emailType, "{{ .Tx.Data.name }}", "{{ .Tx.Data.key }}", "{{ .Tx.Data.expiration_date }}", config_path)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	password, ok := attrs["key"].(string)
-	if !ok {
-		return fmt.Errorf("User key is not a string or does not exit")
+	if val, ok := c.txTemplates.Load(templateName); ok && val.(txTemplate).body == body {
+		return val.(txTemplate).id, nil
 	}
-	expiration_date, ok := attrs[fmt.Sprintf("expiration_date_%s", strings.ToLower(subscriptionType))].(string)
-	if !ok {
-		return fmt.Errorf("Expiration date is not a string or does not exist")
-	}
-	emailType := subscriptionMap[subscriptionType]
-	content, err := c.formatEmailTemplate(emailType, name, password, expiration_date, config_path)
+
+	c.txTemplateMutex.Lock()
+	defer c.txTemplateMutex.Unlock()
+
+	getTemplatesService := c.Client.NewGetTemplatesService()
+	templates, err := apiResult(getTemplatesService.Do(ctx))
 	if err != nil {
-		return err
+		return 0, err
 	}
-	listname := "tmplist"
-	list, err := c.createList(listname)
-	if err != nil {
-		return err
+
+	templateData := map[string]interface{}{
+		"name":    templateName,
+		"type":    "tx",
+		"subject": txTemplateSubject,
+		"body":    body,
 	}
-	err = c.AddToList(subscriberEmail, listname)
+	for _, template := range templates {
+		if template.Type != "tx" || template.Name != templateName {
+			continue
+		}
+		if template.Body != body {
+			endpoint := fmt.Sprintf("/templates/%d", template.Id)
+			if c.dryRun(ctx, "Update transactional template "+templateName, http.MethodPut, endpoint, templateData) {
+				return template.Id, nil
+			}
+			c.logInfo(ctx, "Updating transactional template.", "template", templateName, "template_id", template.Id)
+			err = c.callAPI(ctx, http.MethodPut, endpoint, templateData, nil)
+			if err != nil {
+				return 0, err
+			}
+		}
+		c.txTemplates.Store(templateName, txTemplate{id: template.Id, body: body})
+		return template.Id, nil
+	}
+
+	if c.dryRun(ctx, "Create transactional template "+templateName, http.MethodPost, "/templates", templateData) {
+		return 0, nil
+	}
+
+	c.logInfo(ctx, "Creating transactional template.", "template", templateName)
+	var template listmonk.Template
+	err = c.callAPI(ctx, http.MethodPost, "/templates", templateData, &template)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	campaignID, err := c.CreateCampaign("tmpcampaign", "DPP credentials", []uint{list.Id}, content, "html")
+
+	c.txTemplates.Store(templateName, txTemplate{id: template.Id, body: body})
+	return template.Id, nil
+}
+
+// Send DPP credentials email to a subscriber using Listmonk's transactional
+// API. The email template for the subscription type is read from config_path
+// and stored in Listmonk on first use. Returns as soon as Listmonk accepts the
+// message.
+func (c *APIClient) SendEmail(subscriptionType, subscriberEmail, name, config_path string) error {
+	return c.SendEmailContext(context.Background(), subscriptionType, subscriberEmail, name, config_path)
+}
+
+// SendEmailContext is like SendEmail but uses ctx for all Listmonk requests.
+func (c *APIClient) SendEmailContext(ctx context.Context, subscriptionType, subscriberEmail, name, config_path string) error {
+	return c.SendEmailFromContext(ctx, subscriptionType, subscriberEmail, name, config_path, Sender{})
+}
+
+// Send DPP credentials email like SendEmail, but from sender instead of the
+// sender configured for the subscription type. Empty fields of sender are
+// taken from the configured one.
+func (c *APIClient) SendEmailFrom(subscriptionType, subscriberEmail, name, config_path string, sender Sender) error {
+	return c.SendEmailFromContext(context.Background(), subscriptionType, subscriberEmail, name, config_path, sender)
+}
+
+// SendEmailFromContext is like SendEmailFrom but uses ctx for all Listmonk requests.
+func (c *APIClient) SendEmailFromContext(ctx context.Context, subscriptionType, subscriberEmail, name, config_path string, sender Sender) error {
+	start := time.Now()
+	sender, err := c.resolveSender(subscriptionType, sender)
 	if err != nil {
 		return err
 	}
-	_, err = c.LaunchCampaign(campaignID)
+	c.logInfo(ctx, "Sending email to subscriber.", "email", subscriberEmail, "subscription_type", subscriptionType)
+	attrs, err := c.GetSubscriberAttributesEmailContext(ctx, subscriberEmail)
 	if err != nil {
 		return err
 	}
-	getCampaignService := c.Client.NewGetCampaignService()
-	getCampaignService.Id(campaignID)
-	campaign, err := getCampaignService.Do(context.Background())
+	password, ok := attrs["key"].(string)
+	if !ok {
+		return fmt.Errorf("%w: user key is not a string or does not exist", ErrInvalidAttribute)
+	}
+	expiration_date, ok := attrs[fmt.Sprintf("expiration_date_%s", strings.ToLower(subscriptionType))].(string)
+	if !ok {
+		return fmt.Errorf("%w: expiration date is not a string or does not exist", ErrInvalidAttribute)
+	}
+	emailType := subscriptionMap[subscriptionType]
+	templateID, err := c.getTxTemplateID(ctx, emailType, config_path)
 	if err != nil {
 		return err
 	}
 
-  time.Sleep(10 * time.Second)
-	err = c.deleteCampaign(campaign)
+	message := map[string]interface{}{
+		"subscriber_email": subscriberEmail,
+		"template_id":      templateID,
+		"from_email":       sender.FromEmail,
+		"messenger":        sender.Messenger,
+		"content_type":     "html",
+		"data": map[string]string{
+			"name":            name,
+			"key":             password,
+			"expiration_date": expiration_date,
+		},
+	}
+	if headers := sender.headers(); headers != nil {
+		message["headers"] = headers
+	}
+	if c.dryRun(ctx, "Send email to "+subscriberEmail, http.MethodPost, "/tx", message) {
+		return nil
+	}
+
+	err = c.callAPI(ctx, http.MethodPost, "/tx", message, nil)
 	if err != nil {
 		return err
 	}
-	return c.DeleteList(listname)
+	c.logOK(ctx, start, "Email accepted by Listmonk.", "email", subscriberEmail, "template_id", templateID)
+	return nil
 }
@@ -2,10 +2,15 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -47,6 +52,14 @@ func deleteSubscriber(client *APIClient, id uint) {
 	check(err)
 }
 
+// Wait until the latest send of a campaign, including incremental ones, stops
+func waitForLatestSend(client *APIClient, campaignID uint) {
+	history, err := client.CampaignHistory(campaignID)
+	check(err)
+	_, err = client.WaitForCampaign(history[len(history)-1].CampaignID, WaitOptions{PollInterval: time.Second})
+	check(err)
+}
+
 func deleteList(client *APIClient, id uint) {
 	deleteListService := client.Client.NewDeleteListService()
 	deleteListService.Id(id)
@@ -54,6 +67,65 @@ func deleteList(client *APIClient, id uint) {
 	check(err)
 }
 
+func TestNewClient(t *testing.T) {
+	username := ""
+	password := ""
+
+	t.Run("unreachable server", func(t *testing.T) {
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithTimeout(time.Second))
+
+		assert.Error(t, err)
+		assert.Nil(t, client)
+	})
+
+	t.Run("lazy list loading", func(t *testing.T) {
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithTimeout(time.Second), WithLazyListLoading())
+
+		assert.NoError(t, err)
+		assert.NotNil(t, client)
+
+		// Lists are loaded on first use
+		_, err = client.getListID(context.Background(), "TestList")
+		assert.Error(t, err)
+	})
+
+	t.Run("correct input data", func(t *testing.T) {
+		hostname, exists := os.LookupEnv("LISTMONK_HOSTNAME")
+		if !exists {
+			hostname = "0.0.0.0"
+		}
+		client, err := NewClient(fmt.Sprintf("http://%s:9000", hostname), &username, &password, WithLazyListLoading())
+		require.NoError(t, err)
+
+		list, err := client.createList(context.Background(), "lazy_list")
+		require.NoError(t, err)
+		defer deleteList(client, list.Id)
+
+		id, err := client.getListID(context.Background(), "lazy_list")
+		assert.NoError(t, err)
+		assert.Equal(t, list.Id, id)
+	})
+
+	t.Run("more lists than one page", func(t *testing.T) {
+		client := initAPIClient()
+		lists := make([]*listmonk.List, 25)
+		for i := range lists {
+			list, err := client.createList(context.Background(), fmt.Sprintf("page_list_%d", i))
+			check(err)
+			defer deleteList(client, list.Id)
+			lists[i] = list
+		}
+
+		// A new client has to load all lists from Listmonk
+		client = initAPIClient()
+		for _, list := range lists {
+			id, err := client.getListID(context.Background(), list.Name)
+			assert.NoError(t, err)
+			assert.Equal(t, list.Id, id)
+		}
+	})
+}
+
 func TestCreateSubscriberListIDs(t *testing.T) {
 	client := initAPIClient()
 
@@ -100,9 +172,21 @@ func TestCreateSubscriberListIDs(t *testing.T) {
 
 		id, err := client.CreateSubscriberListIDs(name, email, list_ids, attrs)
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrValidation)
 		assert.Equal(t, uint(0), id)
 	})
+
+	t.Run("duplicate e-mail", func(t *testing.T) {
+		email := "duplicate@example.com"
+		id, err := client.CreateSubscriberListIDs("First", email, []uint{}, nil)
+		require.NoError(t, err)
+		defer deleteSubscriber(client, id)
+
+		_, err = client.CreateSubscriberListIDs("Second", email, []uint{}, nil)
+
+		assert.ErrorIs(t, err, ErrDuplicateEmail)
+		assert.ErrorIs(t, err, ErrConflict)
+	})
 }
 
 func TestCreateSubscriberFromJSON(t *testing.T) {
@@ -124,9 +208,9 @@ func TestCreateSubscriberFromJSON(t *testing.T) {
 
 		// Create TestList if it doesn't exist
 		listName := "TestList"
-		_, err := client.getListID(listName)
+		_, err := client.getListID(context.Background(), listName)
 		if err != nil {
-			_, err := client.createList(listName)
+			_, err := client.createList(context.Background(), listName)
 			assert.NoError(t, err)
 		}
 
@@ -176,7 +260,7 @@ func TestAddSubscribersFromCSV(t *testing.T) {
 
 	t.Run("valid CSV input", func(t *testing.T) {
 		listName := "MSI"
-		list, err := client.createList(listName)
+		list, err := client.createList(context.Background(), listName)
 		assert.NoError(t, err)
 		defer deleteList(client, list.Id)
 		// Prepare a temporary CSV file
@@ -203,11 +287,329 @@ func TestAddSubscribersFromCSV(t *testing.T) {
 		// Cleanup subscribers
 		subscriberEmails := []string{"test1@example.com", "test2@example.com", "test3@example.com"}
 		for _, email := range subscriberEmails {
-			id, err := client.getSubscriberID(email)
+			id, err := client.getSubscriberID(context.Background(), email)
 			assert.NoError(t, err)
 			deleteSubscriber(client, id)
 		}
 	})
+
+	t.Run("reordered columns", func(t *testing.T) {
+		listName := "MSI"
+		list, err := client.createList(context.Background(), listName)
+		require.NoError(t, err)
+		defer deleteList(client, list.Id)
+
+		csvContent := `Email,Expiration date,Duration (years),Date received
+reordered@example.com,2025-09-07,1,2024-09-07`
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString(csvContent)
+		check(err)
+		csvFile.Close()
+
+		err = client.AddSubscribersFromCSV(csvFile.Name(), listName, map[string]string{"reordered@example.com": "password"})
+		require.NoError(t, err)
+
+		id, err := client.getSubscriberID(context.Background(), "reordered@example.com")
+		require.NoError(t, err)
+		defer deleteSubscriber(client, id)
+
+		attributes, err := client.GetSubscriberAttributes(id)
+		require.NoError(t, err)
+		assert.Equal(t, "1", attributes["duration_msi"])
+		assert.Equal(t, "2024-09-07", attributes["created_msi"])
+		assert.Equal(t, "2025-09-07", attributes["expiration_date_msi"])
+		assert.Equal(t, "password", attributes["key"])
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		csvContent := `email,date_received
+missing@example.com,2024-09-07`
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString(csvContent)
+		check(err)
+		csvFile.Close()
+
+		err = client.AddSubscribersFromCSV(csvFile.Name(), "MSI", nil)
+		assert.ErrorIs(t, err, ErrValidation)
+		var missingErr *MissingColumnsError
+		if assert.ErrorAs(t, err, &missingErr) {
+			assert.Equal(t, []string{"duration", "expiration_date"}, missingErr.Columns)
+		}
+	})
+}
+
+func TestAddSubscribersFromCSVWithOptions(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("continue on error", func(t *testing.T) {
+		listName := "MSI"
+		list, err := client.createList(context.Background(), listName)
+		require.NoError(t, err)
+		defer deleteList(client, list.Id)
+
+		csvContent := `duration,email,date_received,expiration
+1,report1@example.com,2024-09-07,2025-09-07
+2,,2024-05-12,2026-05-12
+,,,
+1,report2@example.com,2024-07-01,2025-07-01`
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString(csvContent)
+		check(err)
+		csvFile.Close()
+
+		report, err := client.AddSubscribersFromCSVWithOptions(csvFile.Name(), listName, nil, ImportOptions{ContinueOnError: true})
+		require.NoError(t, err)
+		for _, row := range report.Rows {
+			if row.SubscriberID != 0 {
+				defer deleteSubscriber(client, row.SubscriberID)
+			}
+		}
+
+		require.Equal(t, 4, len(report.Rows))
+		assert.Equal(t, RowCreated, report.Rows[0].Status)
+		assert.Equal(t, RowFailed, report.Rows[1].Status)
+		assert.Equal(t, 3, report.Rows[1].Line)
+		assert.NotEmpty(t, report.Rows[1].Reason)
+		assert.Equal(t, RowSkipped, report.Rows[2].Status)
+		assert.Equal(t, RowCreated, report.Rows[3].Status)
+
+		// Importing again changes nothing
+		report, err = client.AddSubscribersFromCSVWithOptions(csvFile.Name(), listName, nil, ImportOptions{ContinueOnError: true})
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Count(RowSkipped))
+		assert.Equal(t, 1, report.Count(RowFailed))
+	})
+
+	t.Run("concurrent import", func(t *testing.T) {
+		listName := "MSI"
+		list, err := client.createList(context.Background(), listName)
+		require.NoError(t, err)
+		defer deleteList(client, list.Id)
+
+		var sb strings.Builder
+		sb.WriteString("duration,email,date_received,expiration\n")
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(&sb, "1,concurrent%d@example.com,2024-09-07,2025-09-07\n", i)
+		}
+		// The same subscriber again, with a longer subscription
+		sb.WriteString("2,concurrent0@example.com,2024-09-07,2026-09-07\n")
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString(sb.String())
+		check(err)
+		csvFile.Close()
+
+		var progress []ImportProgress
+		report, err := client.AddSubscribersFromCSVWithOptions(csvFile.Name(), listName, nil, ImportOptions{
+			Concurrency: 4,
+			Progress:    func(p ImportProgress) { progress = append(progress, p) },
+		})
+		for _, row := range report.Rows {
+			if row.Status == RowCreated {
+				defer deleteSubscriber(client, row.SubscriberID)
+			}
+		}
+		require.NoError(t, err)
+
+		require.Equal(t, 21, len(report.Rows))
+		for i, row := range report.Rows {
+			assert.Equal(t, i+2, row.Line)
+		}
+		assert.Equal(t, 20, report.Count(RowCreated))
+		assert.Equal(t, RowUpdated, report.Rows[20].Status)
+
+		require.Equal(t, 21, len(progress))
+		assert.Equal(t, ImportProgress{Processed: 21, Created: 20, Updated: 1}, progress[20])
+
+		attributes, err := client.GetSubscriberAttributesEmail("concurrent0@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "2026-09-07", attributes["expiration_date_msi"])
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		csvContent := `duration,email,date_received,expiration
+2,,2024-05-12,2026-05-12
+1,report3@example.com,2024-07-01,2025-07-01`
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString(csvContent)
+		check(err)
+		csvFile.Close()
+
+		report, err := client.AddSubscribersFromCSVWithOptions(csvFile.Name(), "MSI", nil, ImportOptions{})
+		assert.ErrorIs(t, err, ErrValidation)
+		require.NotNil(t, report)
+		assert.Equal(t, 1, len(report.Rows))
+		assert.Equal(t, RowFailed, report.Rows[0].Status)
+	})
+}
+
+func TestImportReport(t *testing.T) {
+	report := &ImportReport{
+		Header: []string{"duration", "email", "date_received", "expiration"},
+		Rows: []RowResult{
+			{Line: 2, Email: "ok@example.com", Status: RowCreated, SubscriberID: 7, Record: []string{"1", "ok@example.com", "2024-09-07", "2025-09-07"}},
+			{Line: 3, Status: RowFailed, Reason: "missing email", Record: []string{"2", "", "2024-05-12", "2026-05-12"}},
+		},
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteCSV(&buf))
+		assert.Equal(t, "line,email,status,subscriber_id,reason\n2,ok@example.com,created,7,\n3,,failed,,missing email\n", buf.String())
+	})
+
+	t.Run("failed rows", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteFailedCSV(&buf))
+		assert.Equal(t, "duration,email,date_received,expiration\n2,,2024-05-12,2026-05-12\n", buf.String())
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteJSON(&buf))
+		var decoded ImportReport
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, *report, decoded)
+	})
+}
+
+func TestCSVMappingResolve(t *testing.T) {
+	t.Run("default mapping", func(t *testing.T) {
+		layout, err := DefaultCSVMapping().resolve([]string{"Duration (years)", "E-mail", "Date received", "Expiration date"})
+		require.NoError(t, err)
+
+		row, err := layout.parse([]string{"2", " test@example.com ", "2024-05-12", "2026-05-12"}, "MSI")
+		require.NoError(t, err)
+		assert.Equal(t, "test@example.com", row.email)
+		assert.Equal(t, []string{"MSI"}, row.lists)
+		assert.Equal(t, map[string]interface{}{
+			"duration_msi":        "2",
+			"created_msi":         "2024-05-12",
+			"expiration_date_msi": "2026-05-12",
+		}, row.attrs)
+	})
+
+	t.Run("custom mapping", func(t *testing.T) {
+		mapping := CSVMapping{Columns: []CSVColumn{
+			{Names: []string{"mail"}, Field: CSVEmail, Required: true},
+			{Names: []string{"full name"}, Field: CSVName},
+			{Names: []string{"product"}, Field: CSVList},
+			{Names: []string{"serial"}, Field: CSVAttribute, Attribute: "serial_" + ListPlaceholder},
+		}}
+		layout, err := mapping.resolve([]string{"serial", "Full Name", "mail", "product"})
+		require.NoError(t, err)
+
+		row, err := layout.parse([]string{"123", "John Doe", "john@example.com", "Laptop"}, "MSI")
+		require.NoError(t, err)
+		assert.Equal(t, "John Doe", row.name)
+		assert.Equal(t, []string{"Laptop"}, row.lists)
+		assert.Equal(t, map[string]interface{}{"serial_laptop": "123"}, row.attrs)
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		layout, err := DefaultCSVMapping().resolve([]string{"duration", "email", "date_received", "expiration"})
+		require.NoError(t, err)
+
+		_, err = layout.parse([]string{"1", "", "2024-09-07", "2025-09-07"}, "MSI")
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestCreateSubscriber(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("unknown lists", func(t *testing.T) {
+		id, err := client.CreateSubscriber("Unknown", "unknown.lists@example.com", []string{"no_such_list1", "no_such_list2"}, nil)
+
+		assert.ErrorIs(t, err, ErrListNotFound)
+		var unknownErr *UnknownListsError
+		if assert.ErrorAs(t, err, &unknownErr) {
+			assert.Equal(t, []string{"no_such_list1", "no_such_list2"}, unknownErr.Names)
+		}
+		assert.Equal(t, uint(0), id)
+	})
+
+	t.Run("auto-create missing lists", func(t *testing.T) {
+		autoClient := initAPIClient()
+		WithAutoCreateLists()(autoClient)
+
+		id, err := autoClient.CreateSubscriber("Auto", "auto.lists@example.com", []string{"auto_created_list"}, nil)
+		require.NoError(t, err)
+		defer deleteSubscriber(client, id)
+
+		listID, err := autoClient.getListID(context.Background(), "auto_created_list")
+		require.NoError(t, err)
+		defer deleteList(client, listID)
+
+		subscriber, err := autoClient.GetSubscriber(id)
+		require.NoError(t, err)
+		assert.Equal(t, 1, len(subscriber.Lists))
+		assert.Equal(t, listID, subscriber.Lists[0].Id)
+	})
+}
+
+func TestUpsertSubscriber(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("create, update and leave unchanged", func(t *testing.T) {
+		list1, err := client.createList(context.Background(), "upsert_list1")
+		require.NoError(t, err)
+		defer deleteList(client, list1.Id)
+		list2, err := client.createList(context.Background(), "upsert_list2")
+		require.NoError(t, err)
+		defer deleteList(client, list2.Id)
+
+		email := "upsert@example.com"
+		id, action, err := client.UpsertSubscriber(email, "", []string{"upsert_list1"}, map[string]interface{}{"a": "1", "b": 2}, MergeAttributes)
+		require.NoError(t, err)
+		defer deleteSubscriber(client, id)
+		assert.Equal(t, UpsertCreated, action)
+
+		updatedID, action, err := client.UpsertSubscriber(email, "", []string{"upsert_list2"}, map[string]interface{}{"b": 3}, MergeAttributes)
+		require.NoError(t, err)
+		assert.Equal(t, id, updatedID)
+		assert.Equal(t, UpsertUpdated, action)
+
+		subscriber, err := client.GetSubscriber(id)
+		require.NoError(t, err)
+		assert.Equal(t, email, subscriber.Name)
+		assert.Equal(t, 2, len(subscriber.Lists))
+		assert.Equal(t, map[string]interface{}{"a": "1", "b": float64(3)}, subscriber.Attributes)
+
+		_, action, err = client.UpsertSubscriber(email, "", []string{"upsert_list1"}, map[string]interface{}{"b": 3}, MergeAttributes)
+		require.NoError(t, err)
+		assert.Equal(t, UpsertUnchanged, action)
+	})
+
+	t.Run("replace attributes", func(t *testing.T) {
+		email := "upsert.replace@example.com"
+		id, _, err := client.UpsertSubscriber(email, "Before", []string{}, map[string]interface{}{"a": "1"}, ReplaceAttributes)
+		require.NoError(t, err)
+		defer deleteSubscriber(client, id)
+
+		_, action, err := client.UpsertSubscriber(email, "After", []string{}, map[string]interface{}{"b": "2"}, ReplaceAttributes)
+		require.NoError(t, err)
+		assert.Equal(t, UpsertUpdated, action)
+
+		subscriber, err := client.GetSubscriber(id)
+		require.NoError(t, err)
+		assert.Equal(t, "After", subscriber.Name)
+		assert.Equal(t, map[string]interface{}{"b": "2"}, subscriber.Attributes)
+	})
+
+	t.Run("unknown list", func(t *testing.T) {
+		_, _, err := client.UpsertSubscriber("upsert.unknown@example.com", "", []string{"no_such_list"}, nil, MergeAttributes)
+		assert.ErrorIs(t, err, ErrListNotFound)
+	})
 }
 
 func TestCreateSubscriberWithAttributes(t *testing.T) {
@@ -224,14 +626,14 @@ func TestCreateSubscriberWithAttributes(t *testing.T) {
 
 		// Create TestList if it doesn't exist
 		listName := "TestList"
-		_, err := client.getListID(listName)
+		_, err := client.getListID(context.Background(), listName)
 		if err != nil {
-			_, err = client.createList(listName)
+			_, err = client.createList(context.Background(), listName)
 			require.NoError(t, err)
 		}
 
 		// Ensure no existing subscriber with the same email
-		existingID, _ := client.getSubscriberID(email)
+		existingID, _ := client.getSubscriberID(context.Background(), email)
 		if existingID != 0 {
 			_ = client.DeleteSubscriberID(existingID)
 		}
@@ -269,7 +671,7 @@ func TestCreateCampaignHTML(t *testing.T) {
 		subject := "Subject of campaign"
 		// Create a test list
 		listName := "TestList"
-		list, err := client.createList(listName)
+		list, err := client.createList(context.Background(), listName)
 		assert.NoError(t, err)
 		defer deleteList(client, list.Id)
 
@@ -311,7 +713,7 @@ func TestDeleteCampaign(t *testing.T) {
 		body := "Body"
 		// Create a test list
 		listName := "TestList"
-		list, err := client.createList(listName)
+		list, err := client.createList(context.Background(), listName)
 		assert.NoError(t, err)
 		defer deleteList(client, list.Id)
 
@@ -325,7 +727,7 @@ func TestDeleteCampaign(t *testing.T) {
 		check(err)
 
 		// Delete campaign
-		err = client.deleteCampaign(campaign)
+		err = client.deleteCampaign(context.Background(), campaign)
 
 		assert.NoError(t, err)
 
@@ -370,7 +772,7 @@ func TestAddSubscribersToList(t *testing.T) {
 		}
 
 		// Add subscribers to list
-		err = client.addSubscribersToList(subscribers, list)
+		err = client.addSubscribersToList(context.Background(), subscribers, list)
 		assert.NoError(t, err)
 
 		// Check if subscribers were added
@@ -402,7 +804,7 @@ func TestAddSubscribersToList(t *testing.T) {
 			},
 		}
 
-		err = client.addSubscribersToList(subscribers, list)
+		err = client.addSubscribersToList(context.Background(), subscribers, list)
 		assert.Error(t, err)
 	})
 }
@@ -414,7 +816,7 @@ func TestCreateIncCampaign(t *testing.T) {
 		// Set up test - create base campaign and temp list
 		// Create a test list
 		listName := "TestList"
-		list, err := client.createList(listName)
+		list, err := client.createList(context.Background(), listName)
 		assert.NoError(t, err)
 		defer deleteList(client, list.Id)
 
@@ -436,7 +838,7 @@ func TestCreateIncCampaign(t *testing.T) {
 		client.MailingListIDs.Store(tempList.Name, tempList.Id)
 		defer deleteList(client, tempList.Id)
 
-		incCampaign, err := client.createIncCampaign(baseCampaign, tempList)
+		incCampaign, err := client.createIncCampaign(context.Background(), baseCampaign, tempList)
 
 		assert.NoError(t, err)
 		assert.Equal(t, baseCampaign.Name+"_inc", incCampaign.Name)
@@ -534,6 +936,21 @@ func TestLaunchCampaign(t *testing.T) {
 		assert.NotNil(t, campaign)
 		assert.True(t, campaign.Status == "running" || campaign.Status == "finished")
 		assert.Equal(t, baseCampaign.Body, campaign.Body)
+
+		progress, err := client.WaitForCampaign(campaign.Id, WaitOptions{PollInterval: time.Second})
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), progress.Sent)
+
+		// The temporary list is removed by the next resume once the
+		// incremental campaign is finished
+		resumed, err = client.LaunchCampaign(baseCampaign.Id)
+		require.NoError(t, err)
+		assert.False(t, resumed)
+		lists, err := client.Client.NewGetListsService().PerPage("all").Do(context.Background())
+		check(err)
+		for _, l := range lists {
+			assert.False(t, strings.HasPrefix(l.Name, fmt.Sprintf("%s_%d_", tempListPrefix, baseCampaign.Id)), l.Name)
+		}
 	})
 
 	t.Run("resume no new subscribers", func(t *testing.T) {
@@ -588,7 +1005,7 @@ func TestLaunchCampaign(t *testing.T) {
 	t.Run("no such campaign", func(t *testing.T) {
 		resumed, err := client.LaunchCampaign(999999) // Assuming this ID doesn't exist
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCampaignNotFound)
 		assert.False(t, resumed)
 	})
 
@@ -709,7 +1126,8 @@ func TestDeleteSubscriberEmail(t *testing.T) {
 	t.Run("no such email", func(t *testing.T) {
 		err := client.DeleteSubscriberEmail("no.such@email.com")
 
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrSubscriberNotFound)
+		assert.ErrorIs(t, err, ErrNotFound)
 	})
 }
 
@@ -984,7 +1402,7 @@ func TestMailingListIDsConcurrency(t *testing.T) {
 		defer wg.Done()
 
 		// Create list
-		list, err := client.createList(listName)
+		list, err := client.createList(context.Background(), listName)
 		if err != nil {
 			t.Errorf("Error creating list %s: %v", listName, err)
 			return
@@ -992,7 +1410,7 @@ func TestMailingListIDsConcurrency(t *testing.T) {
 		defer deleteList(client, list.Id)
 
 		// Get list ID
-		id, err := client.getListID(listName)
+		id, err := client.getListID(context.Background(), listName)
 		if err != nil {
 			t.Errorf("Error getting list ID for %s: %v", listName, err)
 			return
@@ -1006,7 +1424,7 @@ func TestMailingListIDsConcurrency(t *testing.T) {
 		for j := 0; j < 5; j++ {
 			go func(j int) {
 				// Concurrently get list ID
-				concurrentID, err := client.getListID(listName)
+				concurrentID, err := client.getListID(context.Background(), listName)
 				if err != nil {
 					t.Errorf("Goroutine %d: Error getting list ID for %s: %v", j, listName, err)
 				} else if concurrentID != list.Id {
@@ -1035,9 +1453,9 @@ func TestSubscriberAttributes(t *testing.T) {
 
 		// Create TestList if it doesn't exist
 		listName := "TestList"
-		_, err := client.getListID(listName)
+		_, err := client.getListID(context.Background(), listName)
 		if err != nil {
-			_, err = client.createList(listName)
+			_, err = client.createList(context.Background(), listName)
 			require.NoError(t, err)
 		}
 
@@ -1094,9 +1512,9 @@ func TestSubscriberAttributes(t *testing.T) {
 
 		// Create TestList if it doesn't exist
 		listName := "TestList"
-		_, err := client.getListID(listName)
+		_, err := client.getListID(context.Background(), listName)
 		if err != nil {
-			_, err = client.createList(listName)
+			_, err = client.createList(context.Background(), listName)
 			require.NoError(t, err)
 		}
 
@@ -1135,7 +1553,7 @@ func TestListSubscribers(t *testing.T) {
 	t.Run("Correct data", func(t *testing.T) {
 		listName := "sometestlist"
 		// Create list and subscribers
-		list, err := client.createList(listName)
+		list, err := client.createList(context.Background(), listName)
 		check(err)
 		defer deleteList(client, list.Id)
 
@@ -1174,6 +1592,23 @@ func TestListSubscribers(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("more subscribers than one page", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "pagedsubscriberlist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		for i := 0; i < 25; i++ {
+			attrs := map[string]interface{}{"expiration_date_pagedsubscriberlist": "2025-09-07"}
+			id, err := client.CreateSubscriberListIDs(fmt.Sprintf("Paged %d", i), fmt.Sprintf("paged%d@example.com", i), []uint{list.Id}, attrs)
+			check(err)
+			defer deleteSubscriber(client, id)
+		}
+
+		result, err := client.ListSubscribers("pagedsubscriberlist")
+		require.NoError(t, err)
+		assert.Equal(t, 25, len(result))
+	})
 }
 
 func TestFormatEmailTemplate(t *testing.T) {
@@ -1227,6 +1662,7 @@ Best regards,
 		expiration_date := "2025-08-10"
 		_, err := client.formatEmailTemplate(emailType, name, password, expiration_date, "../config")
 		assert.ErrorContains(t, err, "Wrong email type! Available types:")
+		assert.ErrorIs(t, err, ErrInvalidTemplateType)
 	})
 }
 
@@ -1252,6 +1688,20 @@ func TestSendEmail(t *testing.T) {
     check(err)
     err = client.SendEmail(subscriptionType, email, "John Doe", "../config")
     assert.NoError(t, err)
+
+    // No temporary lists or campaigns are left behind
+    getListsService := client.Client.NewGetListsService()
+    lists, err := getListsService.Do(context.Background())
+    check(err)
+    for _, list := range lists {
+      assert.NotEqual(t, "tmplist", list.Name)
+    }
+    getCampaignsService := client.Client.NewGetCampaignsService()
+    campaigns, err := getCampaignsService.Do(context.Background())
+    check(err)
+    for _, campaign := range campaigns {
+      assert.NotEqual(t, "tmpcampaign", campaign.Name)
+    }
   })
 
   t.Run("wrong subscription type", func(t *testing.T) {
@@ -1276,3 +1726,1732 @@ func TestSendEmail(t *testing.T) {
     assert.ErrorContains(t, err, "Wrong email type! Available types")
   })
 }
+
+func TestGetTxTemplateID(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("correct input data", func(t *testing.T) {
+		id, err := client.getTxTemplateID(context.Background(), "desktop", "../config")
+		require.NoError(t, err)
+		defer func() {
+			deleteTemplateService := client.Client.NewDeleteTemplateService()
+			deleteTemplateService.Id(id)
+			check(deleteTemplateService.Do(context.Background()))
+		}()
+
+		getTemplateService := client.Client.NewGetTemplateService()
+		getTemplateService.Id(id)
+		template, err := getTemplateService.Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "tx", template.Type)
+		assert.Equal(t, "dpp_desktop", template.Name)
+		assert.Contains(t, template.Body, "{{ .Tx.Data.key }}")
+
+		// The template is reused on subsequent calls
+		cachedID, err := client.getTxTemplateID(context.Background(), "desktop", "../config")
+		assert.NoError(t, err)
+		assert.Equal(t, id, cachedID)
+	})
+
+	t.Run("changed template file", func(t *testing.T) {
+		configPath := t.TempDir()
+		check(os.WriteFile(filepath.Join(configPath, "dpp_laptop"), []byte("Hello ${name}, your key is ${key}"), 0o644))
+
+		id, err := client.getTxTemplateID(context.Background(), "laptop", configPath)
+		require.NoError(t, err)
+		defer func() {
+			deleteTemplateService := client.Client.NewDeleteTemplateService()
+			deleteTemplateService.Id(id)
+			check(deleteTemplateService.Do(context.Background()))
+		}()
+
+		check(os.WriteFile(filepath.Join(configPath, "dpp_laptop"), []byte("Hi ${name}, your key is ${key}"), 0o644))
+		updatedID, err := client.getTxTemplateID(context.Background(), "laptop", configPath)
+		require.NoError(t, err)
+		assert.Equal(t, id, updatedID)
+
+		getTemplateService := client.Client.NewGetTemplateService()
+		getTemplateService.Id(id)
+		template, err := getTemplateService.Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "Hi {{ .Tx.Data.name }}, your key is {{ .Tx.Data.key }}", template.Body)
+	})
+
+	t.Run("wrong email type", func(t *testing.T) {
+		_, err := client.getTxTemplateID(context.Background(), "???", "../config")
+		assert.ErrorContains(t, err, "Wrong email type! Available types:")
+	})
+}
+
+func TestEq(t *testing.T) {
+	t.Run("string value", func(t *testing.T) {
+		query, err := Eq(Column("subscribers.email"), "john.doe@example.com").Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "subscribers.email = 'john.doe@example.com'", query)
+	})
+
+	t.Run("value with quotes", func(t *testing.T) {
+		query, err := Eq(Column("subscribers.email"), "o'brien@example.com' OR '1'='1").Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "subscribers.email = 'o''brien@example.com'' OR ''1''=''1'", query)
+	})
+
+	t.Run("invalid column", func(t *testing.T) {
+		_, err := Eq(Column("email; DROP TABLE subscribers"), "x").Build()
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported value", func(t *testing.T) {
+		_, err := Eq(Column("id"), []string{"x"}).Build()
+		assert.Error(t, err)
+	})
+}
+
+func TestIn(t *testing.T) {
+	t.Run("correct input data", func(t *testing.T) {
+		query, err := In(Column("list_id"), uint(1), uint(3)).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "list_id IN (1,3)", query)
+	})
+
+	t.Run("no values", func(t *testing.T) {
+		query, err := In(Column("list_id")).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "FALSE", query)
+	})
+}
+
+func TestInSubquery(t *testing.T) {
+	t.Run("correct input data", func(t *testing.T) {
+		date := time.Date(2024, 9, 7, 12, 0, 0, 0, time.UTC)
+		query, err := InSubquery(Column("id"), "subscriber_lists", "subscriber_id", And(
+			Gt(Column("created_at"), date),
+			In(Column("list_id"), uint(2)),
+		)).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "id IN (SELECT subscriber_id FROM subscriber_lists WHERE (created_at > '2024-09-07T12:00:00+00:00') AND (list_id IN (2)))", query)
+	})
+
+	t.Run("invalid table", func(t *testing.T) {
+		_, err := InSubquery(Column("id"), "subscriber_lists s", "subscriber_id", And()).Build()
+		assert.Error(t, err)
+	})
+}
+
+func TestAttr(t *testing.T) {
+	t.Run("nested attribute", func(t *testing.T) {
+		query, err := Eq(Attr("preferences", "news'letter"), "true").Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "subscribers.attribs->'preferences'->>'news''letter' = 'true'", query)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		_, err := Eq(Attr(), "x").Build()
+		assert.Error(t, err)
+	})
+}
+
+func TestOr(t *testing.T) {
+	t.Run("correct input data", func(t *testing.T) {
+		query, err := Or(
+			Eq(Lower(Column("subscribers.email")), "a@example.com"),
+			Not(Eq(Column("subscribers.status"), "blocklisted")),
+		).Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "(LOWER(subscribers.email) = 'a@example.com') OR (NOT (subscribers.status = 'blocklisted'))", query)
+	})
+
+	t.Run("error in subquery", func(t *testing.T) {
+		_, err := Or(Eq(Column("id"), 1), Eq(Column("bad column"), 1)).Build()
+		assert.Error(t, err)
+	})
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	t.Run("status codes", func(t *testing.T) {
+		assert.ErrorIs(t, &APIError{StatusCode: 404, Message: "Not Found"}, ErrNotFound)
+		assert.ErrorIs(t, &APIError{StatusCode: 409, Message: "E-mail already exists."}, ErrConflict)
+		assert.ErrorIs(t, &APIError{StatusCode: 400, Message: "Invalid email."}, ErrValidation)
+		assert.ErrorIs(t, &APIError{StatusCode: 403, Message: "Permission denied"}, ErrUnauthorized)
+		assert.NotErrorIs(t, &APIError{StatusCode: 500, Message: "Internal error"}, ErrNotFound)
+	})
+
+	t.Run("not found reported as bad request", func(t *testing.T) {
+		err := &APIError{StatusCode: 400, Message: "Campaign not found."}
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("wrapped go-listmonk error", func(t *testing.T) {
+		err := apiError(&listmonk.APIError{Code: 404, Message: "Not Found"})
+		var apiErr *APIError
+		assert.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, 404, apiErr.StatusCode)
+	})
+}
+
+func TestConsoleHandler(t *testing.T) {
+	t.Run("message with attributes", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewConsoleHandler(&buf, nil))
+
+		logger.With("list", "MSI").Log(context.Background(), LevelOK, "Added subscriber.", "email", "john.doe@example.com", "name", "John Doe")
+
+		line := buf.String()
+		assert.Contains(t, line, "OK")
+		assert.Contains(t, line, "] Added subscriber.")
+		assert.Contains(t, line, " list=MSI")
+		assert.Contains(t, line, " email=john.doe@example.com")
+		assert.Contains(t, line, ` name="John Doe"`)
+	})
+
+	t.Run("level below minimum", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewConsoleHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+		logger.Info("Fetching campaign data.")
+
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Run("structured fields", func(t *testing.T) {
+		username := ""
+		password := ""
+		var buf bytes.Buffer
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+		require.NoError(t, err)
+
+		client.logOK(context.Background(), time.Now(), "Deleted list.", "list", "MSI")
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "Deleted list.", entry["msg"])
+		assert.Equal(t, "MSI", entry["list"])
+		assert.Contains(t, entry, "duration")
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("import and launch", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "dryrunlist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		campaignID, err := client.CreateCampaignHTML("Dry run campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString("duration,email,date_received,expiration\n1,dryrun@example.com,2024-09-07,2025-09-07\n")
+		check(err)
+		csvFile.Close()
+
+		plan := NewPlan()
+		dryClient := initAPIClient()
+		WithDryRun(plan)(dryClient)
+
+		launched, err := dryClient.AddCSVAndSendCampaign(csvFile.Name(), "dryrunlist", map[string]string{"dryrun@example.com": "password"})
+		require.NoError(t, err)
+		assert.True(t, launched)
+
+		writes := plan.Writes()
+		require.Equal(t, 2, len(writes))
+		assert.Equal(t, "POST", writes[0].Method)
+		assert.Equal(t, "/subscribers", writes[0].Endpoint)
+		assert.Equal(t, "PUT", writes[1].Method)
+		assert.Equal(t, fmt.Sprintf("/campaigns/%d/status", campaignID), writes[1].Endpoint)
+
+		// Nothing was written
+		_, err = client.getSubscriberID(context.Background(), "dryrun@example.com")
+		assert.ErrorIs(t, err, ErrSubscriberNotFound)
+
+		getCampaignService := client.Client.NewGetCampaignService()
+		getCampaignService.Id(campaignID)
+		campaign, err := getCampaignService.Do(context.Background())
+		check(err)
+		assert.Equal(t, "draft", campaign.Status)
+	})
+}
+
+func TestPlan(t *testing.T) {
+	t.Run("output", func(t *testing.T) {
+		plan := NewPlan()
+		plan.add(PlannedWrite{Description: "Create list MSI", Method: "POST", Endpoint: "/lists", Body: map[string]interface{}{"name": "MSI"}})
+		plan.add(PlannedWrite{Description: "Delete list MSI", Method: "DELETE", Endpoint: "/lists/0"})
+
+		assert.Equal(t, "POST /lists: Create list MSI\nDELETE /lists/0: Delete list MSI\n", plan.String())
+
+		var buf bytes.Buffer
+		require.NoError(t, plan.WriteJSON(&buf))
+		var writes []map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &writes))
+		require.Equal(t, 2, len(writes))
+		assert.Equal(t, map[string]interface{}{"name": "MSI"}, writes[0]["body"])
+		assert.NotContains(t, writes[1], "body")
+	})
+}
+
+func TestRunImport(t *testing.T) {
+	username := ""
+	password := ""
+	client, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	check(err)
+
+	// Jobs that are skipped or failed without sending requests
+	jobs := func(n int) func() (importJob, error) {
+		i := 0
+		return func() (importJob, error) {
+			if i == n {
+				return importJob{}, io.EOF
+			}
+			i++
+			job := importJob{result: RowResult{Line: i + 1}}
+			if i%2 == 0 {
+				job.err = fmt.Errorf("%w: bad row", ErrValidation)
+			} else {
+				job.skip = "empty row"
+			}
+			return job, nil
+		}
+	}
+
+	t.Run("continue on error", func(t *testing.T) {
+		calls := 0
+		report, err := client.runImport(context.Background(), jobs(50), ImportOptions{
+			ContinueOnError: true,
+			Concurrency:     3,
+			Progress:        func(ImportProgress) { calls++ },
+		})
+		require.NoError(t, err)
+		require.Equal(t, 50, len(report.Rows))
+		for i, row := range report.Rows {
+			assert.Equal(t, i+2, row.Line)
+		}
+		assert.Equal(t, 25, report.Count(RowFailed))
+		assert.Equal(t, 25, report.Count(RowSkipped))
+		assert.Equal(t, 50, calls)
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		report, err := client.runImport(context.Background(), jobs(50), ImportOptions{})
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Equal(t, 2, len(report.Rows))
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		readErr := errors.New("read error")
+		report, err := client.runImport(context.Background(), func() (importJob, error) { return importJob{}, readErr }, ImportOptions{})
+		assert.ErrorIs(t, err, readErr)
+		assert.Equal(t, 0, len(report.Rows))
+	})
+}
+
+func TestBulkImportSubscribersFromCSV(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("new and existing subscribers", func(t *testing.T) {
+		listName := "MSI"
+		list, err := client.createList(context.Background(), listName)
+		check(err)
+		defer deleteList(client, list.Id)
+
+		existingID, err := client.CreateSubscriber("Existing", "bulk.existing@example.com", []string{listName}, map[string]interface{}{"key": "old", "other": "kept"})
+		check(err)
+		defer deleteSubscriber(client, existingID)
+
+		csvContent := `duration,email,date_received,expiration
+1,bulk.new@example.com,2024-09-07,2025-09-07
+2,bulk.existing@example.com,2024-05-12,2026-05-12`
+		csvFile, err := os.CreateTemp("", "subscribers_*.csv")
+		check(err)
+		defer os.Remove(csvFile.Name())
+		_, err = csvFile.WriteString(csvContent)
+		check(err)
+		csvFile.Close()
+
+		passwords := map[string]string{"bulk.new@example.com": "new", "bulk.existing@example.com": "new"}
+		result, err := client.BulkImportSubscribersFromCSV(csvFile.Name(), listName, passwords, ImportOptions{PollInterval: 100 * time.Millisecond})
+		require.NoError(t, err)
+		newID, err := client.getSubscriberID(context.Background(), "bulk.new@example.com")
+		require.NoError(t, err)
+		defer deleteSubscriber(client, newID)
+
+		assert.Equal(t, 2, result.Total)
+		assert.Equal(t, 2, result.Imported)
+
+		attributes, err := client.GetSubscriberAttributes(newID)
+		require.NoError(t, err)
+		assert.Equal(t, "new", attributes["key"])
+		assert.Equal(t, "2025-09-07", attributes["expiration_date_msi"])
+
+		attributes, err = client.GetSubscriberAttributes(existingID)
+		require.NoError(t, err)
+		assert.Equal(t, "old", attributes["key"])
+		assert.Equal(t, "kept", attributes["other"])
+		assert.Equal(t, "2026-05-12", attributes["expiration_date_msi"])
+	})
+}
+
+func TestGroupRowsByLists(t *testing.T) {
+	t.Run("merge and group", func(t *testing.T) {
+		rows := []importRow{
+			{email: "a@example.com", lists: []string{"MSI"}, attrs: map[string]interface{}{"x": "1"}},
+			{email: "b@example.com", lists: []string{"PCEngines"}, attrs: map[string]interface{}{}},
+			{email: "c@example.com", lists: []string{"MSI"}, attrs: map[string]interface{}{}},
+		}
+		rows[0] = mergeRows(rows[0], importRow{email: "a@example.com", name: "A", lists: []string{"MSI"}, attrs: map[string]interface{}{"x": "2", "y": "3"}})
+		assert.Equal(t, "A", rows[0].name)
+		assert.Equal(t, []string{"MSI"}, rows[0].lists)
+		assert.Equal(t, map[string]interface{}{"x": "2", "y": "3"}, rows[0].attrs)
+
+		groups := groupRowsByLists(rows)
+		require.Equal(t, 2, len(groups))
+		assert.Equal(t, []string{"a@example.com", "c@example.com"}, mapping(groups[0], func(r importRow) string { return r.email }))
+		assert.Equal(t, []string{"b@example.com"}, mapping(groups[1], func(r importRow) string { return r.email }))
+	})
+}
+
+func TestAddSubscribersFromJSONL(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("valid and invalid entries", func(t *testing.T) {
+		listName := "MSI"
+		list, err := client.createList(context.Background(), listName)
+		check(err)
+		defer deleteList(client, list.Id)
+
+		input := `{"name": "JSON 1", "email": "json1@example.com", "lists": ["MSI"], "attrs": {"key": "k1"}}
+
+{"name": "No lists", "email": "json2@example.com", "lists": []}
+{"name": "JSON 3", "email": "json3@example.com", "lists": ["MSI"]}
+not json`
+		report, err := client.AddSubscribersFromJSONL(strings.NewReader(input), ImportOptions{ContinueOnError: true})
+		require.NoError(t, err)
+		for _, row := range report.Rows {
+			if row.SubscriberID != 0 {
+				defer deleteSubscriber(client, row.SubscriberID)
+			}
+		}
+
+		require.Equal(t, 4, len(report.Rows))
+		assert.Equal(t, []int{1, 3, 4, 5}, mapping(report.Rows, func(r RowResult) int { return r.Line }))
+		assert.Equal(t, []RowStatus{RowCreated, RowFailed, RowCreated, RowFailed}, mapping(report.Rows, func(r RowResult) RowStatus { return r.Status }))
+
+		attributes, err := client.GetSubscriberAttributes(report.Rows[0].SubscriberID)
+		require.NoError(t, err)
+		assert.Equal(t, "k1", attributes["key"])
+
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteFailedJSONL(&buf))
+		assert.Equal(t, "{\"name\": \"No lists\", \"email\": \"json2@example.com\", \"lists\": []}\nnot json\n", buf.String())
+	})
+}
+
+func TestAddSubscribersFromJSONArray(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("valid entries", func(t *testing.T) {
+		listName := "MSI"
+		list, err := client.createList(context.Background(), listName)
+		check(err)
+		defer deleteList(client, list.Id)
+
+		input := `[
+			{"name": "Array 1", "email": "array1@example.com", "lists": ["MSI"]},
+			{"name": "Array 2", "email": "array2@example.com", "lists": ["MSI"], "attrs": {"city": "Gdansk"}}
+		]`
+		report, err := client.AddSubscribersFromJSONArray(strings.NewReader(input), ImportOptions{Concurrency: 2})
+		require.NoError(t, err)
+		for _, row := range report.Rows {
+			if row.SubscriberID != 0 {
+				defer deleteSubscriber(client, row.SubscriberID)
+			}
+		}
+
+		require.Equal(t, 2, len(report.Rows))
+		assert.Equal(t, 2, report.Count(RowCreated))
+		assert.Equal(t, "array2@example.com", report.Rows[1].Email)
+	})
+
+	t.Run("not an array", func(t *testing.T) {
+		_, err := client.AddSubscribersFromJSONArray(strings.NewReader(`{"email": "x@example.com"}`), ImportOptions{})
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
+
+func TestEndJSONArray(t *testing.T) {
+	// Decoder positioned after the last entry of the array
+	decoder := func(input string) *json.Decoder {
+		decoder := json.NewDecoder(strings.NewReader(input))
+		_, err := decoder.Token()
+		check(err)
+		var entry json.RawMessage
+		check(decoder.Decode(&entry))
+		return decoder
+	}
+
+	assert.NoError(t, endJSONArray(decoder(`[{"email": "a@example.com"}]`)))
+	assert.NoError(t, endJSONArray(decoder("[{\"email\": \"a@example.com\"}]\n")))
+	assert.ErrorIs(t, endJSONArray(decoder(`[{"email": "a@example.com"}`)), ErrValidation)
+	assert.ErrorIs(t, endJSONArray(decoder(`[{"email": "a@example.com"}][{"email": "b@example.com"}]`)), ErrValidation)
+	assert.ErrorIs(t, endJSONArray(decoder(`[{"email": "a@example.com"}] x`)), ErrValidation)
+}
+
+func TestJSONImportJob(t *testing.T) {
+	t.Run("valid entry", func(t *testing.T) {
+		job := jsonImportJob(3, []byte(`{"name": " John ", "email": " john@example.com ", "lists": ["MSI"], "attrs": {"a": 1}}`))
+		require.NoError(t, job.err)
+		assert.Equal(t, 3, job.result.Line)
+		assert.Equal(t, "john@example.com", job.row.email)
+		assert.Equal(t, "John", job.row.name)
+		assert.Equal(t, []string{"MSI"}, job.row.lists)
+		assert.Nil(t, job.row.key)
+	})
+
+	t.Run("invalid entries", func(t *testing.T) {
+		for _, data := range []string{
+			`{"email": "", "lists": ["MSI"]}`,
+			`{"email": "john@example.com"}`,
+			`{"email": "john@example.com", "lists": [" "]}`,
+			`{"email": 1}`,
+		} {
+			job := jsonImportJob(1, []byte(data))
+			assert.ErrorIs(t, job.err, ErrValidation, data)
+		}
+	})
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	t.Run("valid addresses", func(t *testing.T) {
+		for input, expected := range map[string]string{
+			"john@example.com":             "john@example.com",
+			"  John@Example.COM ":          "John@example.com",
+			"john.doe+tag@Sub.Example.org": "john.doe+tag@sub.example.org",
+		} {
+			email, err := NormalizeEmail(input)
+			assert.NoError(t, err, input)
+			assert.Equal(t, expected, email)
+		}
+	})
+
+	t.Run("invalid addresses", func(t *testing.T) {
+		for _, input := range []string{"", "john", "john@", "@example.com", "john@@example.com", "John <john@example.com>", "john@localhost", "john doe@example.com"} {
+			_, err := NormalizeEmail(input)
+			assert.ErrorIs(t, err, ErrInvalidEmail, input)
+			assert.ErrorIs(t, err, ErrValidation, input)
+		}
+	})
+}
+
+func TestCheckEmail(t *testing.T) {
+	username := ""
+	password := ""
+	domains, err := os.CreateTemp("", "domains_*.txt")
+	check(err)
+	defer os.Remove(domains.Name())
+	_, err = domains.WriteString("# Disposable domains\nmailinator.com\n\nTrashMail.com\n")
+	check(err)
+	domains.Close()
+
+	t.Run("fix", func(t *testing.T) {
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithDisposableDomains(domains.Name()))
+		require.NoError(t, err)
+
+		email, err := client.checkEmail(" john@EXAMPLE.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "john@example.com", email)
+
+		for _, input := range []string{"john@mailinator.com", "john@TrashMail.com", "john@eu.mailinator.com"} {
+			_, err = client.checkEmail(input)
+			assert.ErrorIs(t, err, ErrDisposableEmail, input)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithEmailPolicy(RejectUnnormalizedEmails))
+		require.NoError(t, err)
+
+		_, err = client.checkEmail("john@EXAMPLE.com")
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+		email, err := client.checkEmail("John@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "John@example.com", email)
+	})
+
+	t.Run("missing domains file", func(t *testing.T) {
+		_, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithDisposableDomains("no_such_file.txt"))
+		assert.Error(t, err)
+	})
+}
+
+func TestEmailNormalization(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("create and look up", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "normalizationlist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		id, err := client.CreateSubscriber("Mixed Case", "  Mixed.Case@Example.COM ", []string{"normalizationlist"}, nil)
+		require.NoError(t, err)
+		defer deleteSubscriber(client, id)
+
+		subscriber, err := client.GetSubscriber(id)
+		require.NoError(t, err)
+		assert.Equal(t, "mixed.case@example.com", strings.ToLower(subscriber.Email))
+
+		// Lookups ignore case
+		foundID, err := client.getSubscriberID(context.Background(), "MIXED.CASE@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, id, foundID)
+
+		// Upserting a case variant updates the same subscriber
+		upsertID, action, err := client.UpsertSubscriber("mixed.case@EXAMPLE.com", "", []string{"normalizationlist"}, map[string]interface{}{"city": "Gdansk"}, MergeAttributes)
+		require.NoError(t, err)
+		assert.Equal(t, id, upsertID)
+		assert.Equal(t, UpsertUpdated, action)
+	})
+
+	t.Run("invalid email", func(t *testing.T) {
+		id, err := client.CreateSubscriber("Invalid", "not an email", []string{"MSI"}, nil)
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+		assert.Equal(t, uint(0), id)
+	})
+}
+
+func TestDuplicateKey(t *testing.T) {
+	t.Run("variants", func(t *testing.T) {
+		assert.Equal(t, "john@example.com", DuplicateKey("john@example.com"))
+		assert.Equal(t, "john@example.com", DuplicateKey(" John@Example.COM"))
+		assert.Equal(t, "john@example.com", DuplicateKey("john+shop@example.com"))
+		assert.Equal(t, "+john@example.com", DuplicateKey("+john@example.com"))
+		assert.NotEqual(t, DuplicateKey("john.doe@example.com"), DuplicateKey("johndoe@example.com"))
+	})
+}
+
+func TestPlanMerge(t *testing.T) {
+	t.Run("conflict rules", func(t *testing.T) {
+		group := DuplicateGroup{Key: "john@example.com", Subscribers: []*listmonk.Subscriber{
+			{
+				Id:     1,
+				Email:  "john@example.com",
+				Status: "enabled",
+				Lists:  []listmonk.SubscriberList{{Id: 10, SubscriptionStatus: "confirmed"}},
+				Attributes: map[string]interface{}{
+					"key":                 "first",
+					"city":                "Gdansk",
+					"duration_msi":        "1",
+					"created_msi":         "2024-09-07",
+					"expiration_date_msi": "2025-09-07",
+				},
+			},
+			{
+				Id:    2,
+				Email: "John+shop@example.com",
+				Lists: []listmonk.SubscriberList{
+					{Id: 11, SubscriptionStatus: "unconfirmed"},
+					{Id: 12, SubscriptionStatus: "unsubscribed"},
+				},
+				Attributes: map[string]interface{}{
+					"key":                       "second",
+					"city":                      "Gdansk",
+					"duration_msi":              "2",
+					"created_msi":               "2024-10-01",
+					"expiration_date_msi":       "2026-10-01",
+					"expiration_date_pcengines": "2025-01-01",
+				},
+			},
+		}}
+
+		plan := PlanMerge(group)
+		assert.Equal(t, uint(1), plan.PrimaryID)
+		assert.Equal(t, "john@example.com", plan.Email)
+		assert.Equal(t, []uint{2}, plan.DuplicateIDs)
+		assert.Equal(t, "enabled", plan.Status)
+		assert.Equal(t, []uint{10, 11, 12}, plan.Lists)
+		assert.Equal(t, []uint{12}, plan.Unsubscribed)
+		assert.Equal(t, map[string]interface{}{
+			"key":                       "first",
+			"city":                      "Gdansk",
+			"duration_msi":              "2",
+			"created_msi":               "2024-09-07",
+			"expiration_date_msi":       "2026-10-01",
+			"expiration_date_pcengines": "2025-01-01",
+		}, plan.Attributes)
+		assert.Equal(t, []AttributeConflict{{Attribute: "key", Kept: "first", Dropped: []interface{}{"second"}}}, plan.Conflicts)
+	})
+
+	t.Run("opt-outs win", func(t *testing.T) {
+		group := DuplicateGroup{Key: "jane@example.com", Subscribers: []*listmonk.Subscriber{
+			{
+				Id:     1,
+				Email:  "jane@example.com",
+				Status: "enabled",
+				Lists:  []listmonk.SubscriberList{{Id: 10, SubscriptionStatus: "confirmed"}},
+			},
+			{
+				Id:     2,
+				Email:  "Jane@example.com",
+				Status: "blocklisted",
+				Lists:  []listmonk.SubscriberList{{Id: 10, SubscriptionStatus: "unsubscribed"}},
+			},
+		}}
+
+		plan := PlanMerge(group)
+		assert.Equal(t, "blocklisted", plan.Status)
+		assert.Equal(t, []uint{10}, plan.Lists)
+		assert.Equal(t, []uint{10}, plan.Unsubscribed)
+	})
+}
+
+func TestMergeDuplicateSubscribers(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("plus addressing", func(t *testing.T) {
+		list1, err := client.createList(context.Background(), "duplicatelist1")
+		check(err)
+		defer deleteList(client, list1.Id)
+		list2, err := client.createList(context.Background(), "duplicatelist2")
+		check(err)
+		defer deleteList(client, list2.Id)
+
+		primaryID, err := client.CreateSubscriber("Primary", "merge.me@example.com", []string{"duplicatelist1"}, map[string]interface{}{"key": "first"})
+		check(err)
+		defer deleteSubscriber(client, primaryID)
+		duplicateID, err := client.CreateSubscriber("Duplicate", "merge.me+shop@example.com", []string{"duplicatelist2"}, map[string]interface{}{"key": "second", "expiration_date_msi": "2026-01-01"})
+		check(err)
+
+		groups, err := client.FindDuplicateSubscribers()
+		require.NoError(t, err)
+		var group *DuplicateGroup
+		for i := range groups {
+			if groups[i].Key == "merge.me@example.com" {
+				group = &groups[i]
+			}
+		}
+		require.NotNil(t, group)
+		require.Equal(t, 2, len(group.Subscribers))
+
+		plan := PlanMerge(*group)
+		assert.Equal(t, primaryID, plan.PrimaryID)
+
+		err = client.MergeDuplicateSubscribers([]MergePlan{plan})
+		require.NoError(t, err)
+
+		subscriber, err := client.GetSubscriber(primaryID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(subscriber.Lists))
+		assert.Equal(t, "first", subscriber.Attributes["key"])
+		assert.Equal(t, "2026-01-01", subscriber.Attributes["expiration_date_msi"])
+
+		_, err = client.GetSubscriber(duplicateID)
+		assert.ErrorIs(t, err, ErrSubscriberNotFound)
+	})
+
+	t.Run("unsubscribed duplicate", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "duplicatelist_unsub")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		primaryID, err := client.CreateSubscriber("Primary", "optout@example.com", []string{"duplicatelist_unsub"}, nil)
+		check(err)
+		defer deleteSubscriber(client, primaryID)
+		duplicateID, err := client.CreateSubscriber("Duplicate", "optout+news@example.com", []string{"duplicatelist_unsub"}, nil)
+		check(err)
+		unsubscribeService := client.Client.NewUpdateSubscribersListsService()
+		unsubscribeService.Ids([]uint{duplicateID})
+		unsubscribeService.ListIds([]uint{list.Id})
+		unsubscribeService.Action("unsubscribe")
+		_, err = unsubscribeService.Do(context.Background())
+		check(err)
+
+		primary, err := client.GetSubscriber(primaryID)
+		check(err)
+		duplicate, err := client.GetSubscriber(duplicateID)
+		check(err)
+		plan := PlanMerge(DuplicateGroup{Key: "optout@example.com", Subscribers: []*listmonk.Subscriber{primary, duplicate}})
+		assert.Equal(t, []uint{list.Id}, plan.Unsubscribed)
+
+		require.NoError(t, client.MergeDuplicateSubscribers([]MergePlan{plan}))
+
+		subscriber, err := client.GetSubscriber(primaryID)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(subscriber.Lists))
+		assert.Equal(t, "unsubscribed", subscriber.Lists[0].SubscriptionStatus)
+	})
+}
+
+func TestTempListName(t *testing.T) {
+	t.Run("unique names", func(t *testing.T) {
+		campaign := &listmonk.Campaign{Id: 7}
+		first := tempListName(campaign)
+		second := tempListName(campaign)
+		assert.NotEqual(t, first, second)
+		assert.True(t, strings.HasPrefix(first, "temp_list_7_"))
+		assert.True(t, isTempList(first))
+		assert.True(t, isTempList("temp_list"))
+		assert.False(t, isTempList("temp_list2"))
+		assert.False(t, isTempList("MSI"))
+	})
+}
+
+func TestCleanupTempLists(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("orphaned lists", func(t *testing.T) {
+		orphan, err := client.createList(context.Background(), "temp_list_0_1")
+		check(err)
+		regular, err := client.createList(context.Background(), "not_temp_list")
+		check(err)
+		defer deleteList(client, regular.Id)
+
+		// Only consider lists of this test, other tests may be resuming
+		// campaigns at the same time
+		match := func(name string) bool {
+			return isTempList(name) && (name == orphan.Name || name == regular.Name)
+		}
+
+		// Recent lists are kept
+		deleted, err := client.cleanupTempLists(context.Background(), time.Hour, match)
+		require.NoError(t, err)
+		assert.NotContains(t, deleted, orphan.Name)
+
+		deleted, err = client.cleanupTempLists(context.Background(), 0, match)
+		require.NoError(t, err)
+		assert.Equal(t, []string{orphan.Name}, deleted)
+
+		_, err = client.getListID(context.Background(), orphan.Name)
+		assert.ErrorIs(t, err, ErrListNotFound)
+	})
+
+	t.Run("lists of unfinished campaigns", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "temp_list_0_2")
+		check(err)
+		defer deleteList(client, list.Id)
+		campaignID, err := client.CreateCampaignHTML("Temporary list campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		// The list is kept regardless of its age while its campaign may
+		// still send
+		deleted, err := client.cleanupTempLists(context.Background(), 0, func(name string) bool { return name == list.Name })
+		require.NoError(t, err)
+		assert.Empty(t, deleted)
+	})
+}
+
+func TestIncCampaignName(t *testing.T) {
+	campaign := &listmonk.Campaign{Id: 3, Name: "Update (v1.2)"}
+
+	t.Run("numbering", func(t *testing.T) {
+		assert.Equal(t, "Update (v1.2)_inc", incCampaignName(campaign, 1))
+		assert.Equal(t, "Update (v1.2)_inc_2", incCampaignName(campaign, 2))
+		assert.Equal(t, 1, incCampaignNumber(campaign, "Update (v1.2)_inc"))
+		assert.Equal(t, 12, incCampaignNumber(campaign, "Update (v1.2)_inc_12"))
+		assert.Equal(t, 0, incCampaignNumber(campaign, "Update (v1x2)_inc"))
+		assert.Equal(t, 0, incCampaignNumber(campaign, "Update (v1.2)_inc_x"))
+		assert.Equal(t, "incremental_of_3", incrementalTag(campaign.Id))
+	})
+}
+
+func TestCampaignHistory(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("two resumes", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "historylist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		subscriberIDs := make([]uint, 3)
+		for i := range subscriberIDs {
+			var lists []uint
+			if i == 0 {
+				lists = []uint{list.Id}
+			}
+			subscriberIDs[i], err = client.CreateSubscriberListIDs(fmt.Sprintf("History %d", i), fmt.Sprintf("history%d@example.com", i), lists, nil)
+			check(err)
+			defer deleteSubscriber(client, subscriberIDs[i])
+		}
+
+		campaignID, err := client.CreateCampaignHTML("History campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		launched, err := client.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		require.True(t, launched)
+		waitForLatestSend(client, campaignID)
+
+		for _, id := range subscriberIDs[1:] {
+			subscriber, err := client.GetSubscriber(id)
+			check(err)
+			check(client.AddToList(subscriber.Email, "historylist"))
+
+			resumed, err := client.LaunchCampaign(campaignID)
+			require.NoError(t, err)
+			require.True(t, resumed)
+			waitForLatestSend(client, campaignID)
+		}
+
+		history, err := client.CampaignHistory(campaignID)
+		require.NoError(t, err)
+		for _, send := range history[1:] {
+			defer deleteCampaign(client, send.CampaignID)
+		}
+
+		require.Equal(t, 3, len(history))
+		assert.False(t, history[0].Incremental)
+		assert.Equal(t, "History campaign_inc", history[1].Name)
+		assert.Equal(t, "History campaign_inc_2", history[2].Name)
+		assert.True(t, history[2].Incremental)
+		assert.Equal(t, uint(1), history[0].ToSend)
+		assert.Equal(t, uint(1), history[1].Sent)
+		assert.Equal(t, uint(1), history[2].Sent)
+	})
+
+	t.Run("campaigns with the same name", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "historylist_samename")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		firstID, err := client.CreateCampaignHTML("Same name campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, firstID)
+		secondID, err := client.CreateCampaignHTML("Same name campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, secondID)
+
+		second, err := client.getCampaign(context.Background(), secondID)
+		check(err)
+		incCampaign, err := client.copyCampaign(context.Background(), "Create incremental campaign", second, incCampaignName(second, 1), []uint{list.Id}, []string{incrementalTag(secondID)})
+		check(err)
+		defer deleteCampaign(client, incCampaign.Id)
+
+		first, err := client.getCampaign(context.Background(), firstID)
+		check(err)
+		incCampaigns, err := client.getIncCampaigns(context.Background(), first)
+		require.NoError(t, err)
+		assert.Empty(t, incCampaigns)
+
+		incCampaigns, err = client.getIncCampaigns(context.Background(), second)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(incCampaigns))
+		assert.Equal(t, incCampaign.Id, incCampaigns[0].Id)
+	})
+}
+
+func TestFileLedger(t *testing.T) {
+	t.Run("persist and reload", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ledger.json")
+		ledger, err := NewFileLedger(path)
+		require.NoError(t, err)
+
+		_, ok, err := ledger.Delivered(context.Background(), 1)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		require.NoError(t, ledger.Record(context.Background(), 1, []uint{3, 2}))
+		require.NoError(t, ledger.Record(context.Background(), 1, []uint{2, 5}))
+		require.NoError(t, ledger.Record(context.Background(), 4, nil))
+
+		reloaded, err := NewFileLedger(path)
+		require.NoError(t, err)
+		delivered, ok, err := reloaded.Delivered(context.Background(), 1)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[uint]bool{2: true, 3: true, 5: true}, delivered)
+
+		delivered, ok, err = reloaded.Delivered(context.Background(), 4)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Empty(t, delivered)
+	})
+
+	t.Run("corrupted file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ledger.json")
+		check(os.WriteFile(path, []byte("not json"), 0o644))
+		_, err := NewFileLedger(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestLaunchCampaignWithLedger(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("re-added subscriber", func(t *testing.T) {
+		ledger := NewMemoryLedger()
+		ledgerClient := initAPIClient()
+		WithDeliveryLedger(ledger)(ledgerClient)
+
+		list, err := client.createList(context.Background(), "ledgerlist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		// Keep the subscriber on another list so leaving does not delete them
+		otherList, err := client.createList(context.Background(), "ledgerother")
+		check(err)
+		defer deleteList(client, otherList.Id)
+
+		firstID, err := client.CreateSubscriberListIDs("Ledger 1", "ledger1@example.com", []uint{list.Id, otherList.Id}, nil)
+		check(err)
+		defer deleteSubscriber(client, firstID)
+
+		campaignID, err := client.CreateCampaignHTML("Ledger campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		launched, err := ledgerClient.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		require.True(t, launched)
+		waitForLatestSend(client, campaignID)
+
+		delivered, ok, err := ledger.Delivered(context.Background(), campaignID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[uint]bool{firstID: true}, delivered)
+
+		// The first subscriber leaves and joins again, which would make a
+		// join-time based resume send the campaign to them again
+		check(client.RemoveFromList("ledger1@example.com", "ledgerlist"))
+		check(client.AddToList("ledger1@example.com", "ledgerlist"))
+		secondID, err := client.CreateSubscriberListIDs("Ledger 2", "ledger2@example.com", []uint{list.Id}, nil)
+		check(err)
+		defer deleteSubscriber(client, secondID)
+
+		resumed, err := ledgerClient.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		require.True(t, resumed)
+
+		history, err := client.CampaignHistory(campaignID)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		defer deleteCampaign(client, history[1].CampaignID)
+		waitForLatestSend(client, campaignID)
+
+		delivered, _, err = ledger.Delivered(context.Background(), campaignID)
+		require.NoError(t, err)
+		assert.Equal(t, map[uint]bool{firstID: true, secondID: true}, delivered)
+
+		history, err = client.CampaignHistory(campaignID)
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), history[1].ToSend)
+		assert.Equal(t, uint(1), history[1].Sent)
+
+		// Nobody is left to send to
+		resumed, err = ledgerClient.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		assert.False(t, resumed)
+	})
+
+	t.Run("double opt-in list", func(t *testing.T) {
+		ledger := NewMemoryLedger()
+		ledgerClient := initAPIClient()
+		WithDeliveryLedger(ledger)(ledgerClient)
+
+		createListService := client.Client.NewCreateListService()
+		createListService.Name("ledgerlist_double")
+		createListService.Optin("double")
+		list, err := createListService.Do(context.Background())
+		check(err)
+		defer deleteList(client, list.Id)
+
+		subscriberIDs := make([]uint, 2)
+		for i := range subscriberIDs {
+			createSubscriberService := client.Client.NewCreateSubscriberService()
+			createSubscriberService.Name(fmt.Sprintf("Ledger double %d", i))
+			createSubscriberService.Email(fmt.Sprintf("ledgerdouble%d@example.com", i))
+			createSubscriberService.Status("enabled")
+			createSubscriberService.ListIds([]uint{list.Id})
+			// Only the first subscriber confirms the subscription
+			createSubscriberService.PreconfirmSubscriptions(i == 0)
+			subscriber, err := createSubscriberService.Do(context.Background())
+			check(err)
+			subscriberIDs[i] = subscriber.Id
+			defer deleteSubscriber(client, subscriber.Id)
+		}
+
+		campaignID, err := client.CreateCampaignHTML("Ledger double campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		launched, err := ledgerClient.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		require.True(t, launched)
+		waitForLatestSend(client, campaignID)
+
+		// The ledger matches what Listmonk sent
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		require.NoError(t, err)
+		delivered, ok, err := ledger.Delivered(context.Background(), campaignID)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[uint]bool{subscriberIDs[0]: true}, delivered)
+		assert.Equal(t, "finished", campaign.Status)
+		assert.Equal(t, uint(len(delivered)), campaign.Sent)
+
+		// The unconfirmed subscriber is not sent to on resume either
+		resumed, err := ledgerClient.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		assert.False(t, resumed)
+	})
+}
+
+func TestWaitForCampaign(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("wait until finished", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "waitlist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		subscriberID, err := client.CreateSubscriberListIDs("Wait", "wait@example.com", []uint{list.Id}, nil)
+		check(err)
+		defer deleteSubscriber(client, subscriberID)
+
+		campaignID, err := client.CreateCampaignHTML("Wait campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		launched, err := client.LaunchCampaign(campaignID)
+		check(err)
+		require.True(t, launched)
+
+		var updates []CampaignProgress
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		progress, err := client.WaitForCampaignContext(ctx, campaignID, WaitOptions{
+			PollInterval: 100 * time.Millisecond,
+			Progress:     func(p CampaignProgress) { updates = append(updates, p) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "finished", progress.Status)
+		assert.Equal(t, uint(1), progress.Sent)
+		assert.NotEmpty(t, updates)
+		assert.Equal(t, *progress, updates[len(updates)-1])
+	})
+
+	t.Run("draft campaign", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "waitlist_draft")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		campaignID, err := client.CreateCampaignHTML("Wait draft", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		progress, err := client.WaitForCampaign(campaignID, WaitOptions{})
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Equal(t, "draft", progress.Status)
+	})
+
+	t.Run("nonexistent campaign", func(t *testing.T) {
+		_, err := client.WaitForCampaign(999999, WaitOptions{})
+		assert.ErrorIs(t, err, ErrCampaignNotFound)
+	})
+}
+
+func TestCampaignProgressDone(t *testing.T) {
+	for status, done := range map[string]bool{
+		"draft":     false,
+		"scheduled": false,
+		"running":   false,
+		"paused":    true,
+		"cancelled": true,
+		"finished":  true,
+	} {
+		assert.Equal(t, done, CampaignProgress{Status: status}.Done(), status)
+	}
+}
+
+func TestScheduleCampaign(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("schedule, reschedule and unschedule", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "schedulelist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		sendAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		campaignID, err := client.CreateScheduledCampaign("Scheduled campaign", "Subject", []uint{list.Id}, "Body", "html", sendAt)
+		require.NoError(t, err)
+		defer deleteCampaign(client, campaignID)
+
+		scheduled, err := client.ScheduledCampaigns()
+		require.NoError(t, err)
+		var campaign *listmonk.Campaign
+		for _, camp := range scheduled {
+			if camp.Id == campaignID {
+				campaign = camp
+			}
+		}
+		require.NotNil(t, campaign)
+		assert.True(t, sendAt.Equal(campaign.SendAt), campaign.SendAt)
+
+		later := sendAt.Add(48 * time.Hour)
+		require.NoError(t, client.RescheduleCampaign(campaignID, later))
+		campaign, err = client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, "scheduled", campaign.Status)
+		assert.True(t, later.Equal(campaign.SendAt), campaign.SendAt)
+		assert.Equal(t, "Body", campaign.Body)
+
+		require.NoError(t, client.UnscheduleCampaign(campaignID))
+		campaign, err = client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, "draft", campaign.Status)
+		assert.True(t, campaign.SendAt.IsZero(), campaign.SendAt)
+
+		// A draft can be scheduled again but not rescheduled or unscheduled
+		assert.ErrorIs(t, client.RescheduleCampaign(campaignID, later), ErrValidation)
+		assert.ErrorIs(t, client.UnscheduleCampaign(campaignID), ErrValidation)
+		require.NoError(t, client.ScheduleCampaign(campaignID, later))
+	})
+
+	t.Run("send time in the past", func(t *testing.T) {
+		err := client.ScheduleCampaign(1, time.Now().Add(-time.Hour))
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("nonexistent campaign", func(t *testing.T) {
+		err := client.ScheduleCampaign(999999, time.Now().Add(time.Hour))
+		assert.ErrorIs(t, err, ErrCampaignNotFound)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		username := ""
+		password := ""
+		plan := NewPlan()
+		dryClient, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithDryRun(plan))
+		check(err)
+
+		_, err = dryClient.CreateScheduledCampaign("Dry scheduled", "Subject", []uint{1}, "Body", "html", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		writes := plan.Writes()
+		require.Equal(t, 3, len(writes))
+		assert.Equal(t, "/campaigns", writes[0].Endpoint)
+		assert.Equal(t, "PUT", writes[1].Method)
+		assert.Equal(t, "/campaigns/0", writes[1].Endpoint)
+		assert.Equal(t, "/campaigns/0/status", writes[2].Endpoint)
+	})
+}
+
+func TestCampaignLifecycle(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("update and clone draft", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "lifecyclelist")
+		check(err)
+		defer deleteList(client, list.Id)
+		otherList, err := client.createList(context.Background(), "lifecyclelist_other")
+		check(err)
+		defer deleteList(client, otherList.Id)
+
+		campaignID, err := client.CreateCampaignHTML("Lifecycle campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		err = client.UpdateDraftCampaign(campaignID, CampaignChanges{Subject: "New subject", Lists: []uint{otherList.Id}})
+		require.NoError(t, err)
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, "New subject", campaign.Subject)
+		assert.Equal(t, "Body", campaign.Body)
+		require.Len(t, campaign.Lists, 1)
+		assert.Equal(t, otherList.Id, campaign.Lists[0].Id)
+
+		cloneID, err := client.CloneCampaign(campaignID, "Lifecycle clone", []uint{list.Id})
+		require.NoError(t, err)
+		defer deleteCampaign(client, cloneID)
+		clone, err := client.getCampaign(context.Background(), cloneID)
+		check(err)
+		assert.Equal(t, "Lifecycle clone", clone.Name)
+		assert.Equal(t, "draft", clone.Status)
+		assert.Equal(t, campaign.Subject, clone.Subject)
+		assert.Equal(t, campaign.Body, clone.Body)
+		assert.Equal(t, campaign.FromEmail, clone.FromEmail)
+		require.Len(t, clone.Lists, 1)
+		assert.Equal(t, list.Id, clone.Lists[0].Id)
+
+		// Without lists the clone targets the lists of the original
+		sameListsID, err := client.CloneCampaign(campaignID, "Lifecycle clone 2", nil)
+		require.NoError(t, err)
+		defer deleteCampaign(client, sameListsID)
+		sameLists, err := client.getCampaign(context.Background(), sameListsID)
+		check(err)
+		assert.Equal(t, campaign.Lists, sameLists.Lists)
+	})
+
+	t.Run("pause, resume and cancel", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "lifecyclelist_running")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		for i := 0; i < 20; i++ {
+			id, err := client.CreateSubscriberListIDs(fmt.Sprintf("Lifecycle %d", i), fmt.Sprintf("lifecycle%d@example.com", i), []uint{list.Id}, nil)
+			check(err)
+			defer deleteSubscriber(client, id)
+		}
+
+		campaignID, err := client.CreateCampaignHTML("Lifecycle running", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		// Only running and paused campaigns can be paused, resumed or cancelled
+		assert.ErrorIs(t, client.PauseCampaign(campaignID), ErrValidation)
+		assert.ErrorIs(t, client.ResumeCampaign(campaignID), ErrValidation)
+		assert.ErrorIs(t, client.CancelCampaign(campaignID), ErrValidation)
+
+		_, err = client.LaunchCampaign(campaignID)
+		check(err)
+		err = client.PauseCampaign(campaignID)
+		if err != nil {
+			campaign, _ := client.getCampaign(context.Background(), campaignID)
+			if campaign != nil && campaign.Status == "finished" {
+				t.Skip("campaign finished before it could be paused")
+			}
+		}
+		require.NoError(t, err)
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, "paused", campaign.Status)
+
+		require.NoError(t, client.ResumeCampaign(campaignID))
+		campaign, err = client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Contains(t, []string{"running", "finished"}, campaign.Status)
+
+		if campaign.Status == "running" {
+			require.NoError(t, client.CancelCampaign(campaignID))
+			campaign, err = client.getCampaign(context.Background(), campaignID)
+			check(err)
+			assert.Equal(t, "cancelled", campaign.Status)
+		}
+	})
+
+	t.Run("nonexistent campaign", func(t *testing.T) {
+		assert.ErrorIs(t, client.PauseCampaign(999999), ErrCampaignNotFound)
+		_, err := client.CloneCampaign(999999, "Clone", nil)
+		assert.ErrorIs(t, err, ErrCampaignNotFound)
+		assert.ErrorIs(t, client.UpdateDraftCampaign(999999, CampaignChanges{Subject: "Subject"}), ErrCampaignNotFound)
+	})
+}
+
+func TestSendCampaignTest(t *testing.T) {
+	client := initAPIClient()
+
+	list, err := client.createList(context.Background(), "testsendlist")
+	check(err)
+	defer deleteList(client, list.Id)
+	reviewerList, err := client.createList(context.Background(), "testsendreviewers")
+	check(err)
+	defer deleteList(client, reviewerList.Id)
+
+	subscriberID, err := client.CreateSubscriberListIDs("Customer", "testsend_customer@example.com", []uint{list.Id}, nil)
+	check(err)
+	defer deleteSubscriber(client, subscriberID)
+	reviewerID, err := client.CreateSubscriberListIDs("Reviewer", "reviewer@example.com", []uint{reviewerList.Id}, nil)
+	check(err)
+	defer deleteSubscriber(client, reviewerID)
+
+	t.Run("explicit reviewers", func(t *testing.T) {
+		campaignID, err := client.CreateCampaignHTML("Test send campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		require.NoError(t, client.SendCampaignTest(campaignID, []string{"reviewer@example.com"}))
+
+		// A test send does not launch the campaign
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, "draft", campaign.Status)
+	})
+
+	t.Run("no reviewers", func(t *testing.T) {
+		err := client.SendCampaignTest(1, nil)
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("invalid reviewer", func(t *testing.T) {
+		err := client.SendCampaignTest(1, []string{"not an email"})
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+	})
+
+	t.Run("required before launch", func(t *testing.T) {
+		reviewClient := initAPIClient()
+		WithReviewers("reviewer@example.com")(reviewClient)
+		WithRequiredTestSend()(reviewClient)
+
+		campaignID, err := client.CreateCampaignHTML("Reviewed campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		_, err = reviewClient.LaunchCampaign(campaignID)
+		assert.ErrorIs(t, err, ErrTestSendRequired)
+
+		require.NoError(t, reviewClient.SendCampaignTest(campaignID, nil))
+
+		// Changing the campaign requires another test
+		check(client.UpdateDraftCampaign(campaignID, CampaignChanges{Subject: "Changed"}))
+		_, err = reviewClient.LaunchCampaign(campaignID)
+		assert.ErrorIs(t, err, ErrTestSendRequired)
+
+		require.NoError(t, reviewClient.SendCampaignTest(campaignID, nil))
+
+		// The test is seen by a client that did not send it, e.g. a later run
+		launchClient := initAPIClient()
+		WithRequiredTestSend()(launchClient)
+		launched, err := launchClient.LaunchCampaign(campaignID)
+		require.NoError(t, err)
+		assert.True(t, launched)
+	})
+
+	t.Run("required before scheduling", func(t *testing.T) {
+		reviewClient := initAPIClient()
+		WithReviewers("reviewer@example.com")(reviewClient)
+		WithRequiredTestSend()(reviewClient)
+		sendAt := time.Now().Add(24 * time.Hour)
+
+		_, err := reviewClient.CreateScheduledCampaign("Reviewed scheduled campaign", "Subject", []uint{list.Id}, "Body", "html", sendAt)
+		assert.ErrorIs(t, err, ErrTestSendRequired)
+
+		campaignID, err := client.CreateCampaignHTML("Reviewed scheduled campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		err = reviewClient.ScheduleCampaign(campaignID, sendAt)
+		assert.ErrorIs(t, err, ErrTestSendRequired)
+
+		require.NoError(t, reviewClient.SendCampaignTest(campaignID, nil))
+		require.NoError(t, reviewClient.ScheduleCampaign(campaignID, sendAt))
+		defer func() { check(client.UnscheduleCampaign(campaignID)) }()
+
+		// Tests of campaigns that are no longer drafts are sent, but not
+		// recorded
+		require.NoError(t, reviewClient.SendCampaignTest(campaignID, nil))
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, "scheduled", campaign.Status)
+	})
+}
+
+func TestTestedTag(t *testing.T) {
+	campaign := &listmonk.Campaign{
+		Name:    "Campaign",
+		Subject: "Subject",
+		Body:    "Body",
+		Lists:   []listmonk.CampaignList{{Id: 2}, {Id: 1}},
+	}
+	tag := testedTag(campaign, nil)
+	assert.True(t, isTestedTag(tag))
+
+	// Name, tags and list order do not change the sent messages
+	renamed := *campaign
+	renamed.Name = "Renamed"
+	renamed.Tags = []string{tag}
+	renamed.Lists = []listmonk.CampaignList{{Id: 1}, {Id: 2}}
+	assert.Equal(t, tag, testedTag(&renamed, nil))
+
+	changed := *campaign
+	changed.Body = "Changed"
+	assert.NotEqual(t, tag, testedTag(&changed, nil))
+	assert.NotEqual(t, tag, testedTag(campaign, []map[string]string{{"Reply-To": "sales@3mdeb.com"}}))
+}
+
+func TestGroupCampaignStats(t *testing.T) {
+	day := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	campaigns := []campaignData{
+		{Id: 1, Name: "News", CreatedAt: day, StartedAt: day, Sent: 10, ToSend: 10, Views: 5, Clicks: 2, Bounces: 1},
+		{Id: 2, Name: "News_inc_2", CreatedAt: day.Add(48 * time.Hour), Tags: []string{"incremental_of_1"}, Sent: 2, ToSend: 2, Views: 1},
+		{Id: 3, Name: "News_inc", CreatedAt: day.Add(24 * time.Hour), Tags: []string{"incremental_of_1"}, Sent: 3, ToSend: 3, Clicks: 1},
+		{Id: 4, Name: "Other", CreatedAt: day, Sent: 7, ToSend: 8, Views: 4},
+		{Id: 5, Name: "Orphan_inc", CreatedAt: day, Tags: []string{"incremental_of_99"}},
+		{Id: 6, Name: "News_inc_3", CreatedAt: day, Sent: 4, ToSend: 4},
+	}
+
+	stats := groupCampaignStats(campaigns)
+	require.Len(t, stats, 4)
+
+	news := stats[0]
+	assert.Equal(t, uint(1), news.CampaignID)
+	assert.Equal(t, uint(15), news.Sent)
+	assert.Equal(t, uint(15), news.ToSend)
+	assert.Equal(t, uint(6), news.Views)
+	assert.Equal(t, uint(3), news.Clicks)
+	assert.Equal(t, uint(1), news.Bounces)
+	require.Len(t, news.Incremental, 2)
+	// Incremental sends are ordered by creation
+	assert.Equal(t, uint(3), news.Incremental[0].CampaignID)
+	assert.Equal(t, uint(2), news.Incremental[1].CampaignID)
+
+	assert.Equal(t, uint(4), stats[1].CampaignID)
+	assert.Empty(t, stats[1].Incremental)
+	// Incremental campaigns without an original are reported on their own
+	assert.Equal(t, uint(5), stats[2].CampaignID)
+	// Names ending in _inc do not make a campaign incremental
+	assert.Equal(t, uint(6), stats[3].CampaignID)
+	assert.Equal(t, uint(4), stats[3].Sent)
+}
+
+func TestStatsReport(t *testing.T) {
+	report := &StatsReport{Campaigns: []CampaignStats{
+		{
+			CampaignID: 1, Name: "News", Status: "finished", StartedAt: time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC),
+			ToSend: 15, Sent: 15, Views: 6, Clicks: 3, Bounces: 1,
+			Incremental: []CampaignStats{{CampaignID: 3, Name: "News_inc", Sent: 5, ToSend: 5}},
+		},
+		{CampaignID: 4, Name: "Draft", Status: "draft"},
+	}}
+
+	t.Run("csv", func(t *testing.T) {
+		var out strings.Builder
+		require.NoError(t, report.WriteCSV(&out))
+		assert.Equal(t, "campaign_id,name,status,started_at,sends,to_send,sent,views,clicks,bounces\n"+
+			"1,News,finished,2024-09-01T09:00:00Z,2,15,15,6,3,1\n"+
+			"4,Draft,draft,,1,0,0,0,0,0\n", out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out strings.Builder
+		require.NoError(t, report.WriteJSON(&out))
+		var decoded StatsReport
+		require.NoError(t, json.Unmarshal([]byte(out.String()), &decoded))
+		assert.Equal(t, report.Campaigns[0].Incremental, decoded.Campaigns[0].Incremental)
+		assert.Equal(t, report.Campaigns[1], decoded.Campaigns[1])
+	})
+}
+
+func TestGetCampaignStats(t *testing.T) {
+	client := initAPIClient()
+
+	t.Run("resumed campaign", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "statslist")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		firstID, err := client.CreateSubscriberListIDs("Stats 1", "stats1@example.com", []uint{list.Id}, nil)
+		check(err)
+		defer deleteSubscriber(client, firstID)
+
+		campaignID, err := client.CreateCampaignHTML("Stats campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		_, err = client.LaunchCampaign(campaignID)
+		check(err)
+		_, err = client.WaitForCampaign(campaignID, WaitOptions{PollInterval: 100 * time.Millisecond})
+		check(err)
+
+		secondID, err := client.CreateSubscriberListIDs("Stats 2", "stats2@example.com", []uint{list.Id}, nil)
+		check(err)
+		defer deleteSubscriber(client, secondID)
+		_, err = client.LaunchCampaign(campaignID)
+		check(err)
+
+		history, err := client.CampaignHistory(campaignID)
+		check(err)
+		require.Len(t, history, 2)
+		defer deleteCampaign(client, history[1].CampaignID)
+		_, err = client.WaitForCampaign(history[1].CampaignID, WaitOptions{PollInterval: 100 * time.Millisecond})
+		check(err)
+
+		stats, err := client.GetCampaignStats(campaignID)
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), stats.Sent)
+		require.Len(t, stats.Incremental, 1)
+		assert.Equal(t, history[1].CampaignID, stats.Incremental[0].CampaignID)
+		assert.Equal(t, uint(1), stats.Incremental[0].Sent)
+
+		// The incremental campaign can be queried on its own
+		incStats, err := client.GetCampaignStats(history[1].CampaignID)
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), incStats.Sent)
+
+		report, err := client.GetStatsReport(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		var found bool
+		for _, campaign := range report.Campaigns {
+			assert.NotEqual(t, history[1].CampaignID, campaign.CampaignID)
+			if campaign.CampaignID == campaignID {
+				found = true
+				assert.Equal(t, *stats, campaign)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("nonexistent campaign", func(t *testing.T) {
+		_, err := client.GetCampaignStats(999999)
+		assert.ErrorIs(t, err, ErrCampaignNotFound)
+	})
+}
+
+func TestResolveSender(t *testing.T) {
+	username := ""
+	password := ""
+
+	t.Run("defaults and overrides", func(t *testing.T) {
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(),
+			WithSender(Sender{ReplyTo: "support@example.com"}),
+			WithSubscriptionSender("MSI", Sender{FromEmail: "MSI support <msi@example.com>"}))
+		require.NoError(t, err)
+
+		sender, err := client.resolveSender("", Sender{})
+		require.NoError(t, err)
+		assert.Equal(t, Sender{FromEmail: "newsletter@3mdeb.com", ReplyTo: "support@example.com", Messenger: "email"}, sender)
+
+		sender, err = client.resolveSender("MSI", Sender{})
+		require.NoError(t, err)
+		assert.Equal(t, Sender{FromEmail: "MSI support <msi@example.com>", ReplyTo: "support@example.com", Messenger: "email"}, sender)
+
+		// Other subscription types use the client's sender
+		sender, err = client.resolveSender("PCEngines", Sender{})
+		require.NoError(t, err)
+		assert.Equal(t, "newsletter@3mdeb.com", sender.FromEmail)
+
+		sender, err = client.resolveSender("MSI", Sender{Messenger: "postback"})
+		require.NoError(t, err)
+		assert.Equal(t, Sender{FromEmail: "MSI support <msi@example.com>", ReplyTo: "support@example.com", Messenger: "postback"}, sender)
+
+		_, err = client.resolveSender("", Sender{ReplyTo: "not an email"})
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+	})
+
+	t.Run("invalid client sender", func(t *testing.T) {
+		_, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(),
+			WithSender(Sender{FromEmail: "newsletter"}))
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+
+		_, err = NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(),
+			WithSubscriptionSender("MSI", Sender{ReplyTo: "@example.com"}))
+		assert.ErrorIs(t, err, ErrInvalidEmail)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		plan := NewPlan()
+		client, err := NewClient("http://127.0.0.1:1", &username, &password, WithLazyListLoading(), WithDryRun(plan))
+		require.NoError(t, err)
+
+		_, err = client.CreateCampaignFrom("Support notice", "Subject", []uint{1}, "Body", "html", Sender{FromEmail: "support@example.com", ReplyTo: "help@example.com"})
+		require.NoError(t, err)
+		writes := plan.Writes()
+		require.Equal(t, 1, len(writes))
+		body := writes[0].Body.(map[string]interface{})
+		assert.Equal(t, "support@example.com", body["from_email"])
+		assert.Equal(t, "email", body["messenger"])
+		assert.Equal(t, []map[string]string{{"Reply-To": "help@example.com"}}, body["headers"])
+	})
+}
+
+func TestCreateCampaignFrom(t *testing.T) {
+	client := initAPIClient()
+
+	list, err := client.createList(context.Background(), "senderlist")
+	check(err)
+	defer deleteList(client, list.Id)
+
+	sender := Sender{FromEmail: "3mdeb support <support@example.com>", ReplyTo: "help@example.com"}
+	campaignID, err := client.CreateCampaignFrom("Sender campaign", "Subject", []uint{list.Id}, "Body", "html", sender)
+	require.NoError(t, err)
+	defer deleteCampaign(client, campaignID)
+
+	campaign, err := client.getCampaign(context.Background(), campaignID)
+	check(err)
+	assert.Equal(t, sender.FromEmail, campaign.FromEmail)
+	assert.Equal(t, "email", campaign.Messenger)
+	headers, err := client.getCampaignHeaders(context.Background(), campaign)
+	check(err)
+	assert.Equal(t, []map[string]string{{"Reply-To": "help@example.com"}}, headers)
+
+	// Updates and clones keep the sender
+	check(client.UpdateDraftCampaign(campaignID, CampaignChanges{Subject: "New subject"}))
+	headers, err = client.getCampaignHeaders(context.Background(), campaign)
+	check(err)
+	assert.Equal(t, []map[string]string{{"Reply-To": "help@example.com"}}, headers)
+
+	cloneID, err := client.CloneCampaign(campaignID, "Sender clone", nil)
+	check(err)
+	defer deleteCampaign(client, cloneID)
+	clone, err := client.getCampaign(context.Background(), cloneID)
+	check(err)
+	assert.Equal(t, sender.FromEmail, clone.FromEmail)
+	headers, err = client.getCampaignHeaders(context.Background(), clone)
+	check(err)
+	assert.Equal(t, []map[string]string{{"Reply-To": "help@example.com"}}, headers)
+
+	_, err = client.CreateCampaignFrom("Invalid sender", "Subject", []uint{list.Id}, "Body", "html", Sender{FromEmail: "support"})
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+}
+
+func TestSubscriptionSenderOnListName(t *testing.T) {
+	client := initAPIClient()
+	sender := Sender{FromEmail: "MSI support <msi@example.com>", ReplyTo: "msi-help@example.com"}
+	WithSubscriptionSender("senderlist_msi", sender)(client)
+
+	t.Run("create on list name", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "senderlist_msi")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		campaignID, err := client.CreateCampaignHTMLOnListName("MSI campaign", "Subject", "senderlist_msi", "Body")
+		require.NoError(t, err)
+		defer deleteCampaign(client, campaignID)
+
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, sender.FromEmail, campaign.FromEmail)
+		headers, err := client.getCampaignHeaders(context.Background(), campaign)
+		check(err)
+		assert.Equal(t, []map[string]string{{"Reply-To": "msi-help@example.com"}}, headers)
+	})
+
+	t.Run("launch on list name", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "senderlist_msi")
+		check(err)
+		defer deleteList(client, list.Id)
+
+		// Created with the client's sender
+		campaignID, err := client.CreateCampaignHTML("MSI launch campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+
+		launched, err := client.LaunchCampaignListName("senderlist_msi")
+		require.NoError(t, err)
+		require.True(t, launched)
+
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, sender.FromEmail, campaign.FromEmail)
+		headers, err := client.getCampaignHeaders(context.Background(), campaign)
+		check(err)
+		assert.Equal(t, []map[string]string{{"Reply-To": "msi-help@example.com"}}, headers)
+	})
+
+	t.Run("tested draft keeps its sender", func(t *testing.T) {
+		list, err := client.createList(context.Background(), "senderlist_msi")
+		check(err)
+		defer deleteList(client, list.Id)
+		reviewerID, err := client.CreateSubscriberListIDs("Sender reviewer", "senderreviewer@example.com", []uint{list.Id}, nil)
+		check(err)
+		defer deleteSubscriber(client, reviewerID)
+
+		campaignID, err := client.CreateCampaignHTML("MSI tested campaign", "Subject", []uint{list.Id}, "Body")
+		check(err)
+		defer deleteCampaign(client, campaignID)
+		check(client.SendCampaignTest(campaignID, []string{"senderreviewer@example.com"}))
+
+		launched, err := client.LaunchCampaignListName("senderlist_msi")
+		require.NoError(t, err)
+		require.True(t, launched)
+
+		// The campaign is sent as it was reviewed
+		campaign, err := client.getCampaign(context.Background(), campaignID)
+		check(err)
+		assert.Equal(t, defaultSender.FromEmail, campaign.FromEmail)
+	})
+}
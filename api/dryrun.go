@@ -0,0 +1,75 @@
+// File: dryrun.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// A write to Listmonk skipped by a client in dry-run mode
+type PlannedWrite struct {
+	Description string      `json:"description"`
+	Method      string      `json:"method"`
+	Endpoint    string      `json:"endpoint"`
+	Body        interface{} `json:"body,omitempty"`
+}
+
+// Plan collects writes skipped by a client created with WithDryRun, in the
+// order they would have been sent. Resources that would have been created are
+// referred to by ID 0.
+type Plan struct {
+	mutex  sync.Mutex
+	writes []PlannedWrite
+}
+
+// Create an empty plan
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+func (p *Plan) add(write PlannedWrite) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.writes = append(p.writes, write)
+}
+
+// Return a copy of the recorded writes
+func (p *Plan) Writes() []PlannedWrite {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	writes := make([]PlannedWrite, len(p.writes))
+	copy(writes, p.writes)
+	return writes
+}
+
+// Write the plan as an indented JSON array
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p.Writes())
+}
+
+// Describe the plan with one line per write, e.g.
+// "POST /subscribers: Create subscriber test@example.com"
+func (p *Plan) String() string {
+	var sb strings.Builder
+	for _, write := range p.Writes() {
+		fmt.Fprintf(&sb, "%s %s: %s\n", write.Method, write.Endpoint, write.Description)
+	}
+	return sb.String()
+}
+
+// Record a write in the plan instead of sending it if the client is in dry-run
+// mode. Returns true if the write must be skipped.
+func (c *APIClient) dryRun(ctx context.Context, description, method, endpoint string, body interface{}) bool {
+	if c.plan == nil {
+		return false
+	}
+	c.plan.add(PlannedWrite{Description: description, Method: method, Endpoint: endpoint, Body: body})
+	c.logInfo(ctx, "Dry run: skipping write.", "method", method, "endpoint", endpoint, "description", description)
+	return true
+}
@@ -0,0 +1,341 @@
+// File: duplicates.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Number of subscribers fetched per request when scanning all subscribers
+const scanPageSize = 1000
+
+// Subscribers that probably belong to the same person
+type DuplicateGroup struct {
+	// Email all subscribers of the group reduce to, see DuplicateKey
+	Key         string
+	Subscribers []*listmonk.Subscriber
+}
+
+// How a group of duplicates would be merged
+type MergePlan struct {
+	Key string `json:"key"`
+	// Subscriber that is kept
+	PrimaryID uint   `json:"primary_id"`
+	Email     string `json:"email"`
+	// Subscribers that are deleted after merging
+	DuplicateIDs []uint `json:"duplicate_ids"`
+	// Status of the merged subscriber, "blocklisted" if any subscriber of the
+	// group is blocklisted
+	Status string `json:"status"`
+	// Lists and attributes of the merged subscriber
+	Lists []uint `json:"lists"`
+	// Lists the merged subscriber is unsubscribed from, because a subscriber
+	// of the group unsubscribed from them
+	Unsubscribed []uint                 `json:"unsubscribed,omitempty"`
+	Attributes   map[string]interface{} `json:"attributes"`
+	Conflicts    []AttributeConflict    `json:"conflicts,omitempty"`
+}
+
+// Attribute with different values in a group of duplicates
+type AttributeConflict struct {
+	Attribute string        `json:"attribute"`
+	Kept      interface{}   `json:"kept"`
+	Dropped   []interface{} `json:"dropped"`
+}
+
+// Reduce an email to the form shared by its probable duplicates: the address
+// is lowercased and plus-addressing tags are removed, so "John+shop@X.com"
+// becomes "john@x.com".
+func DuplicateKey(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + domain
+}
+
+// Scan all subscribers and group probable duplicates: subscribers whose emails
+// differ only in case or plus-addressing tags. Groups are ordered by key and
+// their subscribers by creation time.
+func (c *APIClient) FindDuplicateSubscribers() ([]DuplicateGroup, error) {
+	return c.FindDuplicateSubscribersContext(context.Background())
+}
+
+// FindDuplicateSubscribersContext is like FindDuplicateSubscribers but uses ctx for all Listmonk requests.
+func (c *APIClient) FindDuplicateSubscribersContext(ctx context.Context) ([]DuplicateGroup, error) {
+	start := time.Now()
+	c.logInfo(ctx, "Scanning subscribers for duplicates.")
+	subscribers, err := c.getAllSubscribers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := map[string][]*listmonk.Subscriber{}
+	for _, subscriber := range subscribers {
+		key := DuplicateKey(subscriber.Email)
+		byKey[key] = append(byKey[key], subscriber)
+	}
+
+	var groups []DuplicateGroup
+	for key, group := range byKey {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].CreatedAt.Equal(group[j].CreatedAt) {
+				return group[i].Id < group[j].Id
+			}
+			return group[i].CreatedAt.Before(group[j].CreatedAt)
+		})
+		groups = append(groups, DuplicateGroup{Key: key, Subscribers: group})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+
+	c.logOK(ctx, start, "Scanned subscribers for duplicates.", "subscribers", len(subscribers), "groups", len(groups))
+	return groups, nil
+}
+
+// Fetch all subscribers page by page
+func (c *APIClient) getAllSubscribers(ctx context.Context) ([]*listmonk.Subscriber, error) {
+	var subscribers []*listmonk.Subscriber
+	for page := uint(1); ; page++ {
+		getSubscribersService := c.Client.NewGetSubscribersService()
+		getSubscribersService.Page(page)
+		getSubscribersService.PerPage(strconv.Itoa(scanPageSize))
+		found, err := apiResult(getSubscribersService.Do(ctx))
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, found...)
+		if len(found) < scanPageSize {
+			return subscribers, nil
+		}
+	}
+}
+
+// Compute how a group of duplicates is merged without changing anything. The
+// oldest subscriber is kept and gets:
+//   - the blocklisted status if any subscriber of the group is blocklisted,
+//   - all lists of any subscriber of the group. If any of them unsubscribed
+//     from a list, the merged subscriber is unsubscribed from it as well,
+//   - for every subscription type, the latest expiration_date_<type> with the
+//     matching duration_<type>, and the earliest created_<type>,
+//   - other attributes of the oldest subscriber that has them, including the
+//     key. Differing values are reported as conflicts.
+func PlanMerge(group DuplicateGroup) MergePlan {
+	primary := group.Subscribers[0]
+	plan := MergePlan{
+		Key:        group.Key,
+		PrimaryID:  primary.Id,
+		Email:      primary.Email,
+		Status:     primary.Status,
+		Attributes: map[string]interface{}{},
+	}
+
+	for _, subscriber := range group.Subscribers {
+		if subscriber.Id != primary.Id {
+			plan.DuplicateIDs = append(plan.DuplicateIDs, subscriber.Id)
+		}
+		if subscriber.Status == "blocklisted" {
+			plan.Status = "blocklisted"
+		}
+		for _, list := range subscriber.Lists {
+			if !slices.Contains(plan.Lists, list.Id) {
+				plan.Lists = append(plan.Lists, list.Id)
+			}
+			if list.SubscriptionStatus == "unsubscribed" && !slices.Contains(plan.Unsubscribed, list.Id) {
+				plan.Unsubscribed = append(plan.Unsubscribed, list.Id)
+			}
+		}
+	}
+	slices.Sort(plan.Lists)
+	slices.Sort(plan.Unsubscribed)
+
+	// Subscription attributes
+	subscriptions := map[string]bool{}
+	for _, subscriber := range group.Subscribers {
+		for key := range subscriber.Attributes {
+			for _, prefix := range []string{"expiration_date_", "duration_", "created_"} {
+				if strings.HasPrefix(key, prefix) {
+					subscriptions[strings.TrimPrefix(key, prefix)] = true
+				}
+			}
+		}
+	}
+	for subscription := range subscriptions {
+		mergeSubscription(plan.Attributes, group.Subscribers, subscription)
+	}
+
+	// Other attributes: the oldest subscriber wins
+	var keys []string
+	for _, subscriber := range group.Subscribers {
+		for key := range subscriber.Attributes {
+			if _, ok := plan.Attributes[key]; !ok && !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var kept interface{}
+		var dropped []interface{}
+		found := false
+		for _, subscriber := range group.Subscribers {
+			value, ok := subscriber.Attributes[key]
+			if !ok {
+				continue
+			}
+			if !found {
+				kept = value
+				found = true
+			} else if !sameValue(kept, value) {
+				dropped = append(dropped, value)
+			}
+		}
+		plan.Attributes[key] = kept
+		if len(dropped) > 0 {
+			plan.Conflicts = append(plan.Conflicts, AttributeConflict{Attribute: key, Kept: kept, Dropped: dropped})
+		}
+	}
+	return plan
+}
+
+// Merge attributes of one subscription type into attrs
+func mergeSubscription(attrs map[string]interface{}, subscribers []*listmonk.Subscriber, subscription string) {
+	expirationKey := "expiration_date_" + subscription
+	durationKey := "duration_" + subscription
+	createdKey := "created_" + subscription
+
+	var latest *listmonk.Subscriber
+	for _, subscriber := range subscribers {
+		expiration, ok := subscriber.Attributes[expirationKey]
+		if !ok {
+			continue
+		}
+		// Dates are stored as YYYY-MM-DD, so they can be compared as text
+		if latest == nil || fmt.Sprint(expiration) > fmt.Sprint(latest.Attributes[expirationKey]) {
+			latest = subscriber
+		}
+	}
+	if latest != nil {
+		attrs[expirationKey] = latest.Attributes[expirationKey]
+		if duration, ok := latest.Attributes[durationKey]; ok {
+			attrs[durationKey] = duration
+		}
+	}
+
+	var earliest interface{}
+	for _, subscriber := range subscribers {
+		created, ok := subscriber.Attributes[createdKey]
+		if ok && (earliest == nil || fmt.Sprint(created) < fmt.Sprint(earliest)) {
+			earliest = created
+		}
+	}
+	if earliest != nil {
+		attrs[createdKey] = earliest
+	}
+
+	// Fall back to the oldest subscriber's duration if no expiration is known
+	if _, ok := attrs[durationKey]; !ok {
+		for _, subscriber := range subscribers {
+			if duration, ok := subscriber.Attributes[durationKey]; ok {
+				attrs[durationKey] = duration
+				break
+			}
+		}
+	}
+}
+
+// Compare attribute values by their JSON encoding
+func sameValue(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+// Merge groups of duplicates according to their plans: the primary subscriber
+// is updated with merged lists and attributes, then the duplicates are
+// deleted. Review the plans (e.g. from PlanMerge) before calling this.
+func (c *APIClient) MergeDuplicateSubscribers(plans []MergePlan) error {
+	return c.MergeDuplicateSubscribersContext(context.Background(), plans)
+}
+
+// MergeDuplicateSubscribersContext is like MergeDuplicateSubscribers but uses ctx for all Listmonk requests.
+func (c *APIClient) MergeDuplicateSubscribersContext(ctx context.Context, plans []MergePlan) error {
+	for _, plan := range plans {
+		err := c.mergeDuplicates(ctx, plan)
+		if err != nil {
+			return fmt.Errorf("merging %s: %w", plan.Key, err)
+		}
+	}
+	return nil
+}
+
+func (c *APIClient) mergeDuplicates(ctx context.Context, plan MergePlan) error {
+	start := time.Now()
+	primary, err := c.GetSubscriberContext(ctx, plan.PrimaryID)
+	if err != nil {
+		return err
+	}
+
+	c.logInfo(ctx, "Merging duplicate subscribers.", "email", plan.Email, "subscriber_id", plan.PrimaryID, "duplicates", plan.DuplicateIDs)
+	if !c.dryRun(ctx, "Merge duplicates into subscriber "+plan.Email, http.MethodPut, fmt.Sprintf("/subscribers/%d", plan.PrimaryID), map[string]interface{}{
+		"email":   primary.Email,
+		"name":    primary.Name,
+		"status":  plan.Status,
+		"lists":   plan.Lists,
+		"attribs": plan.Attributes,
+	}) {
+		service := c.Client.NewUpdateSubscriberService()
+		service.Id(primary.Id)
+		service.Email(primary.Email)
+		service.Name(primary.Name)
+		service.Status(plan.Status)
+		service.ListIds(plan.Lists)
+		service.Attributes(plan.Attributes)
+		_, err = apiResult(service.Do(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Lists added from duplicates are subscribed to, so unsubscribes have to
+	// be applied again
+	if len(plan.Unsubscribed) > 0 && !c.dryRun(ctx, "Unsubscribe merged subscriber "+plan.Email, http.MethodPut, "/subscribers/lists", map[string]interface{}{
+		"ids":             []uint{primary.Id},
+		"action":          "unsubscribe",
+		"target_list_ids": plan.Unsubscribed,
+	}) {
+		service := c.Client.NewUpdateSubscribersListsService()
+		service.Ids([]uint{primary.Id})
+		service.ListIds(plan.Unsubscribed)
+		service.Action("unsubscribe")
+		_, err = apiResult(service.Do(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range plan.DuplicateIDs {
+		err = c.DeleteSubscriberIDContext(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+	c.logOK(ctx, start, "Merged duplicate subscribers.", "email", plan.Email, "subscriber_id", plan.PrimaryID)
+	return nil
+}
@@ -0,0 +1,103 @@
+// File: email.go
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// What to do with email addresses that are valid but not normalized
+type EmailPolicy int
+
+const (
+	// Normalize email addresses before writing them
+	FixEmails EmailPolicy = iota
+	// Reject email addresses that differ from their normalized form
+	RejectUnnormalizedEmails
+)
+
+// Normalize an email address: surrounding whitespace is removed and the domain
+// is lowercased. The local part is kept as is. Returns an error matching
+// ErrInvalidEmail if the address is not a valid RFC 5322 address without a
+// display name.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %w", ErrInvalidEmail, email, err)
+	}
+	if address.Name != "" || address.Address != email {
+		return "", fmt.Errorf("%w: %q: expected a bare address", ErrInvalidEmail, email)
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], email[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, "[") {
+		return "", fmt.Errorf("%w: %q: invalid domain", ErrInvalidEmail, email)
+	}
+	return local + "@" + strings.ToLower(domain), nil
+}
+
+// Read a list of domains, one per line. Blank lines and lines starting with
+// "#" are ignored.
+func loadDomainList(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	domains := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// Check if domain or any of its parent domains is on the disposable list
+func (c *APIClient) isDisposableDomain(domain string) bool {
+	for {
+		if c.disposableDomains[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// Validate an email address before writing a subscriber and normalize it
+// according to the email policy of the client
+func (c *APIClient) checkEmail(email string) (string, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+	if c.emailPolicy == RejectUnnormalizedEmails && normalized != email {
+		return "", fmt.Errorf("%w: %q is not normalized, expected %q", ErrInvalidEmail, email, normalized)
+	}
+
+	domain := normalized[strings.LastIndex(normalized, "@")+1:]
+	if c.isDisposableDomain(domain) {
+		return "", fmt.Errorf("%w: %s", ErrDisposableEmail, normalized)
+	}
+	return normalized, nil
+}
+
+// Prepare an email address for a case-insensitive lookup
+func lookupEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,119 @@
+// File: errors.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Error categories. Every error returned by APIClient that falls into one of
+// these categories matches it with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Specific errors
+var (
+	ErrListNotFound        = &categorizedError{"list not found", ErrNotFound}
+	ErrSubscriberNotFound  = &categorizedError{"could not find subscriber", ErrNotFound}
+	ErrCampaignNotFound    = &categorizedError{"could not find campaign", ErrNotFound}
+	ErrAmbiguousSubscriber = &categorizedError{"query returned too many results", ErrConflict}
+	ErrDuplicateEmail      = &categorizedError{"subscriber with this email already exists", ErrConflict}
+	ErrInvalidTemplateType = &categorizedError{"Wrong email type", ErrValidation}
+	ErrInvalidAttribute    = &categorizedError{"invalid subscriber attribute", ErrValidation}
+	ErrInvalidEmail        = &categorizedError{"invalid email address", ErrValidation}
+	ErrDisposableEmail     = &categorizedError{"email address of a disposable domain", ErrValidation}
+	ErrTestSendRequired    = &categorizedError{"campaign has not been sent as a test", ErrValidation}
+)
+
+// Sentinel error that also belongs to a category, so that e.g.
+// errors.Is(err, ErrNotFound) holds for ErrListNotFound
+type categorizedError struct {
+	msg      string
+	category error
+}
+
+func (e *categorizedError) Error() string {
+	return e.msg
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.category
+}
+
+// APIError is an error response from Listmonk. Use errors.Is with ErrNotFound,
+// ErrConflict, ErrValidation or ErrUnauthorized to check its category.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("listmonk: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Map HTTP status codes to error categories. Listmonk reports some missing
+// resources with 400 Bad Request, so the message is checked as well.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound ||
+			(e.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(e.Message), "not found"))
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// Convert errors returned by go-listmonk into *APIError
+func apiError(err error) error {
+	var listmonkErr *listmonk.APIError
+	if errors.As(err, &listmonkErr) {
+		return &APIError{StatusCode: listmonkErr.Code, Message: listmonkErr.Message}
+	}
+	return err
+}
+
+// Convert the error of a go-listmonk service call into *APIError
+func apiResult[T any](v T, err error) (T, error) {
+	return v, apiError(err)
+}
+
+// UnknownListsError is returned when subscribing to lists that do not exist.
+// It matches ErrListNotFound.
+type UnknownListsError struct {
+	Names []string
+}
+
+func (e *UnknownListsError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrListNotFound, strings.Join(e.Names, ", "))
+}
+
+func (e *UnknownListsError) Unwrap() error {
+	return ErrListNotFound
+}
+
+// MissingColumnsError is returned when a CSV file lacks required columns. It
+// matches ErrValidation.
+type MissingColumnsError struct {
+	Columns []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("missing required CSV columns: %s", strings.Join(e.Columns, ", "))
+}
+
+func (e *MissingColumnsError) Unwrap() error {
+	return ErrValidation
+}
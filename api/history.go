@@ -0,0 +1,144 @@
+// File: history.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// A send of a campaign: its original launch or an incremental resume
+type CampaignSend struct {
+	CampaignID  uint      `json:"campaign_id"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	StartedAt   time.Time `json:"started_at"`
+	Sent        uint      `json:"sent"`
+	ToSend      uint      `json:"to_send"`
+	Incremental bool      `json:"incremental"`
+}
+
+// Prefix of the tags marking incremental campaigns, followed by the ID of the
+// campaign they were created from
+const incrementalTagPrefix = "incremental_of_"
+
+// Tag marking incremental campaigns created from campaign with given ID
+func incrementalTag(id uint) string {
+	return fmt.Sprintf("%s%d", incrementalTagPrefix, id)
+}
+
+// Name of the n-th incremental campaign: "<name>_inc", "<name>_inc_2", ...
+func incCampaignName(campaign *listmonk.Campaign, n int) string {
+	if n <= 1 {
+		return campaign.Name + "_inc"
+	}
+	return fmt.Sprintf("%s_inc_%d", campaign.Name, n)
+}
+
+// Return the number of an incremental campaign from its name, 0 if the name is
+// not one of incCampaignName
+func incCampaignNumber(campaign *listmonk.Campaign, name string) int {
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(campaign.Name) + `_inc(?:_(\d+))?$`)
+	match := re.FindStringSubmatch(name)
+	if match == nil {
+		return 0
+	}
+	if match[1] == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(match[1])
+	return n
+}
+
+// Get incremental campaigns created from campaign, oldest first. Campaigns
+// are matched by tag or, for those created by older versions, by name.
+// Campaigns tagged as incremental campaigns of another campaign with the same
+// name (e.g. a clone) are not matched by name.
+func (c *APIClient) getIncCampaigns(ctx context.Context, campaign *listmonk.Campaign) ([]*listmonk.Campaign, error) {
+	getCampaignsService := c.Client.NewGetCampaignsService()
+	getCampaignsService.PerPage("all")
+	campaigns, err := apiResult(getCampaignsService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	tag := incrementalTag(campaign.Id)
+	var incCampaigns []*listmonk.Campaign
+	for _, camp := range campaigns {
+		if camp.Id == campaign.Id {
+			continue
+		}
+		if slices.Contains(camp.Tags, tag) {
+			incCampaigns = append(incCampaigns, camp)
+			continue
+		}
+		otherParent := slices.ContainsFunc(camp.Tags, func(t string) bool {
+			return strings.HasPrefix(t, incrementalTagPrefix)
+		})
+		if !otherParent && incCampaignNumber(campaign, camp.Name) > 0 {
+			incCampaigns = append(incCampaigns, camp)
+		}
+	}
+	sort.Slice(incCampaigns, func(i, j int) bool {
+		if incCampaigns[i].CreatedAt.Equal(incCampaigns[j].CreatedAt) {
+			return incCampaigns[i].Id < incCampaigns[j].Id
+		}
+		return incCampaigns[i].CreatedAt.Before(incCampaigns[j].CreatedAt)
+	})
+	return incCampaigns, nil
+}
+
+// Get the time of the latest send of campaign, including incremental ones
+func latestSend(campaign *listmonk.Campaign, incCampaigns []*listmonk.Campaign) time.Time {
+	latest := campaign.StartedAt
+	for _, camp := range incCampaigns {
+		if camp.StartedAt.After(latest) {
+			latest = camp.StartedAt
+		}
+	}
+	return latest
+}
+
+// List the original launch of a campaign and all its incremental sends with
+// their recipient counts, oldest first
+func (c *APIClient) CampaignHistory(id uint) ([]CampaignSend, error) {
+	return c.CampaignHistoryContext(context.Background(), id)
+}
+
+// CampaignHistoryContext is like CampaignHistory but uses ctx for all Listmonk requests.
+func (c *APIClient) CampaignHistoryContext(ctx context.Context, id uint) ([]CampaignSend, error) {
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	incCampaigns, err := c.getIncCampaigns(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	send := func(camp *listmonk.Campaign) CampaignSend {
+		return CampaignSend{
+			CampaignID:  camp.Id,
+			Name:        camp.Name,
+			Status:      camp.Status,
+			StartedAt:   camp.StartedAt,
+			Sent:        camp.Sent,
+			ToSend:      camp.ToSend,
+			Incremental: camp.Id != campaign.Id,
+		}
+	}
+
+	history := []CampaignSend{send(campaign)}
+	for _, camp := range incCampaigns {
+		history = append(history, send(camp))
+	}
+	return history, nil
+}
@@ -0,0 +1,464 @@
+// File: import.go
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// What a CSV column is imported as
+type CSVField int
+
+const (
+	CSVEmail CSVField = iota
+	CSVName
+	// Name of the list to subscribe to, overrides the list given to the importer
+	CSVList
+	CSVAttribute
+)
+
+// Placeholder in attribute keys replaced with the lowercased list name
+const ListPlaceholder = "{list}"
+
+// Mapping of a single CSV column
+type CSVColumn struct {
+	// Accepted header names. Headers are normalized before comparing: they are
+	// lowercased, parenthesized parts are removed and other characters than
+	// letters and digits are replaced with "_", so "Duration (years)" matches
+	// "duration".
+	Names []string
+	Field CSVField
+	// Attribute key of CSVAttribute columns, may contain ListPlaceholder
+	Attribute string
+	// Reject files without this column
+	Required bool
+}
+
+// Mapping of CSV columns to subscriber fields
+type CSVMapping struct {
+	Columns []CSVColumn
+}
+
+// Options of subscriber imports
+type ImportOptions struct {
+	// Column mapping of CSV files. DefaultCSVMapping is used if nil.
+	Mapping *CSVMapping
+	// Import remaining rows when a row fails instead of stopping. Failed rows
+	// are listed in the ImportReport.
+	ContinueOnError bool
+	// Maximum number of rows imported at the same time, 1 if not set. Rows
+	// with the same email are always imported one after another.
+	Concurrency int
+	// Called after every imported row. Calls are never concurrent. Bulk
+	// imports report the number of imported subscribers on every status check.
+	Progress func(ImportProgress)
+	// Interval between status checks of bulk imports, 2 seconds if not set
+	PollInterval time.Duration
+}
+
+// Number of rows imported so far
+type ImportProgress struct {
+	Processed int
+	Created   int
+	Updated   int
+	Skipped   int
+	Failed    int
+}
+
+// Mapping of shop exports: Duration (years), Email, Date received and
+// Expiration date columns, stored as duration_<list>, created_<list> and
+// expiration_date_<list> attributes
+func DefaultCSVMapping() CSVMapping {
+	return CSVMapping{Columns: []CSVColumn{
+		{Names: []string{"duration"}, Field: CSVAttribute, Attribute: "duration_" + ListPlaceholder, Required: true},
+		{Names: []string{"email", "e_mail"}, Field: CSVEmail, Required: true},
+		{Names: []string{"date_received", "received", "created"}, Field: CSVAttribute, Attribute: "created_" + ListPlaceholder, Required: true},
+		{Names: []string{"expiration_date", "expiration"}, Field: CSVAttribute, Attribute: "expiration_date_" + ListPlaceholder, Required: true},
+	}}
+}
+
+var (
+	parenthesizedRegexp = regexp.MustCompile(`\([^)]*\)`)
+	separatorRegexp     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Normalize a CSV header name for comparison
+func normalizeHeader(name string) string {
+	name = strings.ToLower(name)
+	name = parenthesizedRegexp.ReplaceAllString(name, "")
+	name = separatorRegexp.ReplaceAllString(name, "_")
+	return strings.Trim(name, "_")
+}
+
+// Column positions of a CSV file, resolved from its header
+type csvLayout struct {
+	email      int
+	name       int
+	list       int
+	attributes map[int]string
+}
+
+// A subscriber read from an import file
+type importRow struct {
+	email string
+	name  string
+	lists []string
+	attrs map[string]interface{}
+	// Key set unless the subscriber already has one, keys are left alone if nil
+	key *string
+}
+
+// Return the function merging attributes of the row with existing ones
+func (r importRow) merge() func(map[string]interface{}) map[string]interface{} {
+	if r.key == nil {
+		return func(existing map[string]interface{}) map[string]interface{} {
+			merged := make(map[string]interface{}, len(existing)+len(r.attrs))
+			maps.Copy(merged, existing)
+			maps.Copy(merged, r.attrs)
+			return merged
+		}
+	}
+	return subscriptionMerge(r.attrs, *r.key)
+}
+
+// Find mapped columns in the header. Returns a *MissingColumnsError if any
+// required column is missing.
+func (m CSVMapping) resolve(header []string) (*csvLayout, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[normalizeHeader(name)] = i
+	}
+
+	layout := &csvLayout{email: -1, name: -1, list: -1, attributes: map[int]string{}}
+	var missing []string
+	for _, column := range m.Columns {
+		index := -1
+		for _, name := range column.Names {
+			if i, ok := positions[normalizeHeader(name)]; ok {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			if column.Required && len(column.Names) > 0 {
+				missing = append(missing, column.Names[0])
+			}
+			continue
+		}
+
+		switch column.Field {
+		case CSVEmail:
+			layout.email = index
+		case CSVName:
+			layout.name = index
+		case CSVList:
+			layout.list = index
+		case CSVAttribute:
+			layout.attributes[index] = column.Attribute
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, &MissingColumnsError{Columns: missing}
+	}
+	if layout.email < 0 {
+		return nil, fmt.Errorf("%w: CSV mapping has no email column", ErrValidation)
+	}
+	return layout, nil
+}
+
+// Convert a CSV record into a subscriber. The list column, if present and
+// not empty, overrides list.
+func (l *csvLayout) parse(record []string, list string) (importRow, error) {
+	field := func(index int) string {
+		if index < 0 || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	row := importRow{
+		email: field(l.email),
+		name:  field(l.name),
+		attrs: make(map[string]interface{}, len(l.attributes)),
+	}
+	if row.email == "" {
+		return row, fmt.Errorf("%w: missing email in record %v", ErrValidation, record)
+	}
+
+	if rowList := field(l.list); rowList != "" {
+		list = rowList
+	}
+	row.lists = []string{list}
+
+	for index, attribute := range l.attributes {
+		key := strings.ReplaceAll(attribute, ListPlaceholder, strings.ToLower(list))
+		row.attrs[key] = field(index)
+	}
+	return row, nil
+}
+
+// Add subscribers from a CSV file with a header row. Columns are matched by
+// their header names according to opts.Mapping. Each subscriber is subscribed
+// to list (or the list given in its row) and gets the key from passwords
+// unless it already has one. The file is read incrementally and rows are
+// imported by up to opts.Concurrency workers.
+//
+// Returns the outcome of every row processed, ordered by line. The import
+// stops at the first failed row unless opts.ContinueOnError is set, in which
+// case failures are only reported in the ImportReport.
+func (c *APIClient) AddSubscribersFromCSVWithOptions(path, list string, passwords map[string]string, opts ImportOptions) (*ImportReport, error) {
+	return c.AddSubscribersFromCSVWithOptionsContext(context.Background(), path, list, passwords, opts)
+}
+
+// AddSubscribersFromCSVWithOptionsContext is like AddSubscribersFromCSVWithOptions but uses ctx for all Listmonk requests.
+func (c *APIClient) AddSubscribersFromCSVWithOptionsContext(ctx context.Context, path, list string, passwords map[string]string, opts ImportOptions) (*ImportReport, error) {
+	start := time.Now()
+	c.logInfo(ctx, "Adding subscribers from CSV to Listmonk.", "path", path, "list", list)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// Rows with missing fields are reported instead of failing the whole file
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: no records found", ErrValidation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := DefaultCSVMapping()
+	if opts.Mapping != nil {
+		mapping = *opts.Mapping
+	}
+	layout, err := mapping.resolve(header)
+	if err != nil {
+		return nil, err
+	}
+
+	next := func() (importJob, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return importJob{}, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		job := importJob{result: RowResult{Line: line, Record: record}}
+		if isEmptyRecord(record) {
+			job.skip = "empty row"
+			return job, nil
+		}
+		job.row, job.err = layout.parse(record, list)
+		key := passwords[job.row.email]
+		job.row.key = &key
+		job.result.Email = job.row.email
+		return job, nil
+	}
+
+	report, err := c.runImport(ctx, next, opts)
+	report.Header = header
+	if err != nil {
+		return report, err
+	}
+
+	c.logImportFinished(ctx, start, report, "path", path)
+	return report, nil
+}
+
+// Log the summary of an import report
+func (c *APIClient) logImportFinished(ctx context.Context, start time.Time, report *ImportReport, args ...any) {
+	args = append(args, "created", report.Count(RowCreated), "updated", report.Count(RowUpdated),
+		"skipped", report.Count(RowSkipped), "failed", report.Count(RowFailed))
+	c.logOK(ctx, start, "Finished importing subscribers.", args...)
+}
+
+// A row read from an import file
+type importJob struct {
+	// Line, record and email of the row
+	result RowResult
+	row    importRow
+	// Why the row could not be parsed
+	err error
+	// Why the row is skipped without importing it
+	skip string
+}
+
+// Outcome of an importJob
+type importOutcome struct {
+	result RowResult
+	err    error
+}
+
+// Import rows returned by next until it returns io.EOF. Rows are distributed
+// to opts.Concurrency workers by email, so that rows of the same subscriber
+// are never imported concurrently. Returns the results ordered by line and the
+// error of the first failed row unless opts.ContinueOnError is set.
+func (c *APIClient) runImport(ctx context.Context, next func() (importJob, error), opts ImportOptions) (*ImportReport, error) {
+	importCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := max(opts.Concurrency, 1)
+	queues := make([]chan importJob, concurrency)
+	outcomes := make(chan importOutcome)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan importJob)
+		wg.Add(1)
+		go func(queue <-chan importJob) {
+			defer wg.Done()
+			for job := range queue {
+				// Drain the queue once the import is stopped
+				if importCtx.Err() != nil {
+					continue
+				}
+				result, err := c.importJob(importCtx, job)
+				if err != nil && !opts.ContinueOnError {
+					cancel()
+				}
+				outcomes <- importOutcome{result, err}
+			}
+		}(queues[i])
+	}
+
+	var readErr error
+	go func() {
+		defer func() {
+			for _, queue := range queues {
+				close(queue)
+			}
+			wg.Wait()
+			close(outcomes)
+		}()
+
+		for importCtx.Err() == nil {
+			job, err := next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				readErr = err
+				cancel()
+				return
+			}
+
+			queue := queues[workerIndex(job.row.email, concurrency)]
+			select {
+			case queue <- job:
+			case <-importCtx.Done():
+			}
+		}
+	}()
+
+	report := &ImportReport{}
+	var progress ImportProgress
+	var firstErr error
+	firstLine := 0
+	for outcome := range outcomes {
+		report.Rows = append(report.Rows, outcome.result)
+		if outcome.err != nil && (firstErr == nil || outcome.result.Line < firstLine) {
+			firstErr = outcome.err
+			firstLine = outcome.result.Line
+		}
+
+		progress.Processed++
+		switch outcome.result.Status {
+		case RowCreated:
+			progress.Created++
+		case RowUpdated:
+			progress.Updated++
+		case RowSkipped:
+			progress.Skipped++
+		case RowFailed:
+			progress.Failed++
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	slices.SortFunc(report.Rows, func(a, b RowResult) int { return a.Line - b.Line })
+	if readErr != nil {
+		return report, readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	if firstErr != nil && !opts.ContinueOnError {
+		return report, fmt.Errorf("line %d: %w", firstLine, firstErr)
+	}
+	return report, nil
+}
+
+// Choose the worker importing rows with given email
+func workerIndex(email string, workers int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(email)))
+	return int(hash.Sum32() % uint32(workers))
+}
+
+// Import a single row. Errors are also recorded in the result.
+func (c *APIClient) importJob(ctx context.Context, job importJob) (RowResult, error) {
+	start := time.Now()
+	result := job.result
+	if job.skip != "" {
+		result.Status = RowSkipped
+		result.Reason = job.skip
+		return result, nil
+	}
+
+	if job.err != nil {
+		result.Status = RowFailed
+		result.Reason = job.err.Error()
+		c.logWarning(ctx, start, "Could not import subscriber.", "line", result.Line, "error", job.err)
+		return result, job.err
+	}
+
+	row := job.row
+	id, action, err := c.upsertSubscriber(ctx, row.email, row.name, row.lists, row.merge())
+	if err != nil {
+		result.Status = RowFailed
+		result.Reason = err.Error()
+		c.logWarning(ctx, start, "Could not import subscriber.", "line", result.Line, "email", row.email, "error", err)
+		return result, err
+	}
+
+	result.SubscriberID = id
+	switch action {
+	case UpsertCreated:
+		result.Status = RowCreated
+	case UpsertUpdated:
+		result.Status = RowUpdated
+	default:
+		result.Status = RowSkipped
+		result.Reason = "subscriber is up to date"
+	}
+	c.logOK(ctx, start, "Imported subscriber.", "email", row.email, "lists", row.lists, "action", action)
+	return result, nil
+}
+
+// Check if all fields of a record are blank
+func isEmptyRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
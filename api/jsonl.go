@@ -0,0 +1,163 @@
+// File: jsonl.go
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Maximum length of a line in JSON Lines imports
+const maxJSONLineSize = 1024 * 1024
+
+// Add subscribers from JSON Lines: one SubscriberInput object per line. Blank
+// lines are skipped. Every entry is validated and upserted like the rows of
+// AddSubscribersFromCSVWithOptions: subscribers are added to the given lists
+// and the given attributes overwrite existing ones. Only opts.ContinueOnError,
+// opts.Concurrency and opts.Progress are used.
+func (c *APIClient) AddSubscribersFromJSONL(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	return c.AddSubscribersFromJSONLContext(context.Background(), r, opts)
+}
+
+// AddSubscribersFromJSONLContext is like AddSubscribersFromJSONL but uses ctx for all Listmonk requests.
+func (c *APIClient) AddSubscribersFromJSONLContext(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	start := time.Now()
+	c.logInfo(ctx, "Adding subscribers from JSON Lines to Listmonk.")
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxJSONLineSize)
+	line := 0
+
+	next := func() (importJob, error) {
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+			return jsonImportJob(line, data), nil
+		}
+		if err := scanner.Err(); err != nil {
+			return importJob{}, fmt.Errorf("line %d: %w", line+1, err)
+		}
+		return importJob{}, io.EOF
+	}
+
+	report, err := c.runImport(ctx, next, opts)
+	if err != nil {
+		return report, err
+	}
+	c.logImportFinished(ctx, start, report)
+	return report, nil
+}
+
+// Add subscribers from a JSON array of SubscriberInput objects. The array is
+// decoded incrementally, entries are handled like in AddSubscribersFromJSONL
+// and reported by their position in the array. A syntax error, a missing end
+// of the array or data after it stops the import even if opts.ContinueOnError
+// is set.
+func (c *APIClient) AddSubscribersFromJSONArray(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	return c.AddSubscribersFromJSONArrayContext(context.Background(), r, opts)
+}
+
+// AddSubscribersFromJSONArrayContext is like AddSubscribersFromJSONArray but uses ctx for all Listmonk requests.
+func (c *APIClient) AddSubscribersFromJSONArrayContext(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	start := time.Now()
+	c.logInfo(ctx, "Adding subscribers from JSON array to Listmonk.")
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return nil, fmt.Errorf("%w: expected a JSON array", ErrValidation)
+	}
+	index := 0
+
+	next := func() (importJob, error) {
+		if !decoder.More() {
+			err := endJSONArray(decoder)
+			if err != nil {
+				return importJob{}, fmt.Errorf("after entry %d: %w", index, err)
+			}
+			return importJob{}, io.EOF
+		}
+		index++
+		var data json.RawMessage
+		err := decoder.Decode(&data)
+		if err != nil {
+			return importJob{}, fmt.Errorf("entry %d: %w", index, err)
+		}
+		return jsonImportJob(index, data), nil
+	}
+
+	report, err := c.runImport(ctx, next, opts)
+	if err != nil {
+		return report, err
+	}
+	c.logImportFinished(ctx, start, report)
+	return report, nil
+}
+
+// Read the end of a JSON array after its last entry and check that nothing
+// follows it, so that truncated and concatenated files are not imported as
+// complete
+func endJSONArray(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return fmt.Errorf("%w: unterminated JSON array", ErrValidation)
+	}
+	if err != nil {
+		return err
+	}
+	if token != json.Delim(']') {
+		return fmt.Errorf("%w: expected end of JSON array, got %v", ErrValidation, token)
+	}
+	_, err = decoder.Token()
+	if err != io.EOF {
+		return fmt.Errorf("%w: data after end of JSON array", ErrValidation)
+	}
+	return nil
+}
+
+// Create an import job from a JSON SubscriberInput object
+func jsonImportJob(line int, data []byte) importJob {
+	job := importJob{result: RowResult{Line: line, Record: []string{string(data)}}}
+
+	var input SubscriberInput
+	err := json.Unmarshal(data, &input)
+	if err != nil {
+		job.err = fmt.Errorf("%w: %w", ErrValidation, err)
+		return job
+	}
+
+	job.row = importRow{
+		email: strings.TrimSpace(input.Email),
+		name:  strings.TrimSpace(input.Name),
+		lists: input.Lists,
+		attrs: input.Attrs,
+	}
+	job.result.Email = job.row.email
+	job.err = validateSubscriberInput(job.row)
+	return job
+}
+
+// Check that an entry of a JSON import can be imported
+func validateSubscriberInput(row importRow) error {
+	if row.email == "" {
+		return fmt.Errorf("%w: missing email", ErrValidation)
+	}
+	if len(row.lists) == 0 {
+		return fmt.Errorf("%w: no lists given for %s", ErrValidation, row.email)
+	}
+	for _, list := range row.lists {
+		if strings.TrimSpace(list) == "" {
+			return fmt.Errorf("%w: empty list name for %s", ErrValidation, row.email)
+		}
+	}
+	return nil
+}
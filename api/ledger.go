@@ -0,0 +1,301 @@
+// File: ledger.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// DeliveryLedger records which subscribers have received each campaign, so
+// that resumed campaigns are sent only to those who have not received them
+// yet. Incremental sends are recorded under the ID of the original campaign.
+type DeliveryLedger interface {
+	// Return IDs of subscribers who received the campaign. ok is false if
+	// nothing has been recorded for the campaign.
+	Delivered(ctx context.Context, campaignID uint) (ids map[uint]bool, ok bool, err error)
+	// Record that subscribers received the campaign
+	Record(ctx context.Context, campaignID uint, subscriberIDs []uint) error
+}
+
+// MemoryLedger is a DeliveryLedger kept in memory, e.g. for tests or
+// short-lived processes
+type MemoryLedger struct {
+	mutex     sync.Mutex
+	campaigns map[uint]map[uint]bool
+}
+
+// Create an empty MemoryLedger
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{campaigns: map[uint]map[uint]bool{}}
+}
+
+func (l *MemoryLedger) Delivered(_ context.Context, campaignID uint) (map[uint]bool, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delivered, ok := l.campaigns[campaignID]
+	if !ok {
+		return nil, false, nil
+	}
+	ids := make(map[uint]bool, len(delivered))
+	for id := range delivered {
+		ids[id] = true
+	}
+	return ids, true, nil
+}
+
+func (l *MemoryLedger) Record(_ context.Context, campaignID uint, subscriberIDs []uint) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record(campaignID, subscriberIDs)
+	return nil
+}
+
+func (l *MemoryLedger) record(campaignID uint, subscriberIDs []uint) {
+	delivered, ok := l.campaigns[campaignID]
+	if !ok {
+		delivered = map[uint]bool{}
+		l.campaigns[campaignID] = delivered
+	}
+	for _, id := range subscriberIDs {
+		delivered[id] = true
+	}
+}
+
+// FileLedger is a DeliveryLedger stored in a JSON file. The file is rewritten
+// atomically after every change.
+type FileLedger struct {
+	MemoryLedger
+	path string
+}
+
+// Open a FileLedger stored at path. The file is created on first write if it
+// does not exist.
+func NewFileLedger(path string) (*FileLedger, error) {
+	ledger := &FileLedger{MemoryLedger: *NewMemoryLedger(), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Campaign IDs are keys of a JSON object, so they are stored as strings
+	var campaigns map[string][]uint
+	err = json.Unmarshal(data, &campaigns)
+	if err != nil {
+		return nil, err
+	}
+	for key, ids := range campaigns {
+		campaignID, err := strconv.ParseUint(key, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		ledger.record(uint(campaignID), ids)
+	}
+	return ledger, nil
+}
+
+func (l *FileLedger) Record(_ context.Context, campaignID uint, subscriberIDs []uint) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.record(campaignID, subscriberIDs)
+	return l.save()
+}
+
+// Write the ledger to a temporary file and move it in place
+func (l *FileLedger) save() error {
+	campaigns := make(map[string][]uint, len(l.campaigns))
+	for campaignID, delivered := range l.campaigns {
+		ids := make([]uint, 0, len(delivered))
+		for id := range delivered {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		campaigns[strconv.FormatUint(uint64(campaignID), 10)] = ids
+	}
+	data, err := json.Marshal(campaigns)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), l.path)
+}
+
+// Get subscribers a campaign is sent to: subscribers of its lists who are not
+// blocklisted and have not unsubscribed from them. On double opt-in lists,
+// only subscribers who confirmed their subscription are sent to.
+func (c *APIClient) getCampaignRecipients(ctx context.Context, campaign *listmonk.Campaign) ([]*listmonk.Subscriber, error) {
+	listIDs := mapping(campaign.Lists, func(l listmonk.CampaignList) uint { return l.Id })
+	getListsService := c.Client.NewGetListsService()
+	getListsService.PerPage("all")
+	lists, err := apiResult(getListsService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+	doubleOptin := map[uint]bool{}
+	for _, list := range lists {
+		doubleOptin[list.Id] = list.Optin == "double"
+	}
+
+	getSubscribersService := c.Client.NewGetSubscribersService()
+	getSubscribersService.ListIds(listIDs)
+	getSubscribersService.PerPage("all")
+	subscribers, err := apiResult(getSubscribersService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []*listmonk.Subscriber
+	for _, subscriber := range subscribers {
+		if subscriber.Status == "blocklisted" {
+			continue
+		}
+		for _, list := range subscriber.Lists {
+			if !slices.Contains(listIDs, list.Id) || list.SubscriptionStatus == "unsubscribed" {
+				continue
+			}
+			if doubleOptin[list.Id] && list.SubscriptionStatus != "confirmed" {
+				continue
+			}
+			recipients = append(recipients, subscriber)
+			break
+		}
+	}
+	return recipients, nil
+}
+
+// Keep the recipients of campaign whose subscription to one of its lists
+// started by cutoff, i.e. who were sent the send of campaign started at
+// cutoff. Subscribers who joined later, including those who left and joined
+// again, are left for the next resume.
+func (c *APIClient) recipientsJoinedBy(ctx context.Context, campaign *listmonk.Campaign, recipients []*listmonk.Subscriber, cutoff time.Time) ([]*listmonk.Subscriber, error) {
+	listIDs := mapping(campaign.Lists, func(l listmonk.CampaignList) interface{} { return l.Id })
+	query, err := InSubquery(Column("id"), "subscriber_lists", "subscriber_id", And(
+		Lte(Column("created_at"), cutoff),
+		In(Column("list_id"), listIDs...),
+		Neq(Column("status"), "unsubscribed"),
+	)).Build()
+	if err != nil {
+		return nil, err
+	}
+	getSubscribersService := c.Client.NewGetSubscribersService()
+	getSubscribersService.Query(query)
+	getSubscribersService.PerPage("all")
+	subscribers, err := apiResult(getSubscribersService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	joined := map[uint]bool{}
+	for _, subscriber := range subscribers {
+		joined[subscriber.Id] = true
+	}
+	return slices.DeleteFunc(slices.Clone(recipients), func(s *listmonk.Subscriber) bool {
+		return !joined[s.Id]
+	}), nil
+}
+
+// Record the recipients of the first launch of campaign in the ledger once it
+// is running: the recipients who had joined its lists when it started
+func (c *APIClient) recordLaunch(ctx context.Context, campaign *listmonk.Campaign) error {
+	if c.ledger == nil || c.plan != nil {
+		return nil
+	}
+
+	// Get the start time set on launch
+	campaign, err := c.getCampaign(ctx, campaign.Id)
+	if err != nil {
+		return err
+	}
+	recipients, err := c.getCampaignRecipients(ctx, campaign)
+	if err != nil {
+		return err
+	}
+	recipients, err = c.recipientsJoinedBy(ctx, campaign, recipients, campaign.StartedAt)
+	if err != nil {
+		return err
+	}
+	return c.recordDelivery(ctx, campaign, recipients)
+}
+
+// Get recipients of a launched campaign who have not received it according to
+// the ledger. If the ledger has no record of the campaign (e.g. it was
+// launched before the ledger was used), it is seeded with current recipients
+// who had joined its lists by the latest send.
+func (c *APIClient) getUndeliveredSubscribers(ctx context.Context, campaign *listmonk.Campaign) ([]*listmonk.Subscriber, error) {
+	recipients, err := c.getCampaignRecipients(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	delivered, ok, err := c.ledger.Delivered(ctx, campaign.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		start := time.Now()
+		incCampaigns, err := c.getIncCampaigns(ctx, campaign)
+		if err != nil {
+			return nil, err
+		}
+		seed, err := c.recipientsJoinedBy(ctx, campaign, recipients, latestSend(campaign, incCampaigns))
+		if err != nil {
+			return nil, err
+		}
+
+		delivered = map[uint]bool{}
+		for _, subscriber := range seed {
+			delivered[subscriber.Id] = true
+		}
+		err = c.recordDelivery(ctx, campaign, seed)
+		if err != nil {
+			return nil, err
+		}
+		c.logOK(ctx, start, "Seeded delivery ledger.", "campaign_id", campaign.Id, "subscribers", len(seed))
+	}
+
+	var undelivered []*listmonk.Subscriber
+	for _, subscriber := range recipients {
+		if !delivered[subscriber.Id] {
+			undelivered = append(undelivered, subscriber)
+		}
+	}
+	c.logInfo(ctx, "Found subscribers who have not received campaign.", "campaign_id", campaign.Id, "subscribers", len(undelivered))
+	return undelivered, nil
+}
+
+// Record in the ledger that subscribers received campaign. Nothing is recorded
+// in dry-run mode.
+func (c *APIClient) recordDelivery(ctx context.Context, campaign *listmonk.Campaign, subscribers []*listmonk.Subscriber) error {
+	if c.ledger == nil || c.plan != nil {
+		return nil
+	}
+	ids := mapping(subscribers, func(s *listmonk.Subscriber) uint { return s.Id })
+	return c.ledger.Record(ctx, campaign.Id, ids)
+}
@@ -0,0 +1,143 @@
+// File: lifecycle.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Changes to a draft campaign. Empty fields are left unchanged.
+type CampaignChanges struct {
+	Subject string
+	Body    string
+	Lists   []uint
+}
+
+// Pause a running campaign. It stops sending until ResumeCampaign is called.
+func (c *APIClient) PauseCampaign(id uint) error {
+	return c.PauseCampaignContext(context.Background(), id)
+}
+
+// PauseCampaignContext is like PauseCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) PauseCampaignContext(ctx context.Context, id uint) error {
+	return c.changeCampaignStatus(ctx, id, "paused", "running")
+}
+
+// Continue sending a paused campaign. To send a finished campaign to new
+// subscribers, use LaunchCampaign.
+func (c *APIClient) ResumeCampaign(id uint) error {
+	return c.ResumeCampaignContext(context.Background(), id)
+}
+
+// ResumeCampaignContext is like ResumeCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) ResumeCampaignContext(ctx context.Context, id uint) error {
+	return c.changeCampaignStatus(ctx, id, "running", "paused")
+}
+
+// Cancel a running or paused campaign. Cancelled campaigns cannot be resumed.
+// Scheduled campaigns are cancelled with UnscheduleCampaign.
+func (c *APIClient) CancelCampaign(id uint) error {
+	return c.CancelCampaignContext(context.Background(), id)
+}
+
+// CancelCampaignContext is like CancelCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) CancelCampaignContext(ctx context.Context, id uint) error {
+	return c.changeCampaignStatus(ctx, id, "cancelled", "running", "paused")
+}
+
+// Set status of a campaign if its current status is one of from
+func (c *APIClient) changeCampaignStatus(ctx context.Context, id uint, status string, from ...string) error {
+	start := time.Now()
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(from, campaign.Status) {
+		return fmt.Errorf("%w: campaign %d is %s, expected %s", ErrValidation, id, campaign.Status, strings.Join(from, " or "))
+	}
+
+	c.logInfo(ctx, "Changing campaign status.", "campaign_id", id, "from", campaign.Status, "to", status)
+	err = c.setCampaignStatus(ctx, campaign, status)
+	if err != nil {
+		return err
+	}
+	c.logOK(ctx, start, "Changed campaign status.", "campaign", campaign.Name, "campaign_id", id, "status", status)
+	return nil
+}
+
+// Create a draft copy of a campaign with a new name, targeting given lists.
+// The copy targets the lists of the original if lists is empty. Returns ID of
+// the new campaign.
+func (c *APIClient) CloneCampaign(id uint, name string, lists []uint) (uint, error) {
+	return c.CloneCampaignContext(context.Background(), id, name, lists)
+}
+
+// CloneCampaignContext is like CloneCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) CloneCampaignContext(ctx context.Context, id uint, name string, lists []uint) (uint, error) {
+	start := time.Now()
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if len(lists) == 0 {
+		lists = mapping(campaign.Lists, func(l listmonk.CampaignList) uint { return l.Id })
+	}
+
+	// A clone of an incremental campaign is not incremental itself, and a
+	// clone has to be sent as a test on its own
+	tags := slices.DeleteFunc(slices.Clone(campaign.Tags), func(tag string) bool {
+		return strings.HasPrefix(tag, incrementalTagPrefix) || isTestedTag(tag)
+	})
+
+	c.logInfo(ctx, "Cloning campaign.", "campaign_id", id, "name", name)
+	clone, err := c.copyCampaign(ctx, "Clone campaign "+campaign.Name+" as "+name, campaign, name, lists, tags)
+	if err != nil {
+		return 0, err
+	}
+	c.logOK(ctx, start, "Cloned campaign.", "campaign_id", id, "clone", name, "clone_id", clone.Id)
+	return clone.Id, nil
+}
+
+// Change subject, body or lists of a draft campaign
+func (c *APIClient) UpdateDraftCampaign(id uint, changes CampaignChanges) error {
+	return c.UpdateDraftCampaignContext(context.Background(), id, changes)
+}
+
+// UpdateDraftCampaignContext is like UpdateDraftCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) UpdateDraftCampaignContext(ctx context.Context, id uint, changes CampaignChanges) error {
+	start := time.Now()
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+	if campaign.Status != "draft" {
+		return fmt.Errorf("%w: campaign %d is %s, only drafts can be updated", ErrValidation, id, campaign.Status)
+	}
+
+	fields := map[string]interface{}{}
+	if changes.Subject != "" {
+		fields["subject"] = changes.Subject
+	}
+	if changes.Body != "" {
+		fields["body"] = changes.Body
+	}
+	if len(changes.Lists) > 0 {
+		fields["lists"] = changes.Lists
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	c.logInfo(ctx, "Updating draft campaign.", "campaign_id", id)
+	_, err = c.updateCampaign(ctx, campaign, fields)
+	if err != nil {
+		return err
+	}
+	c.logOK(ctx, start, "Updated draft campaign.", "campaign", campaign.Name, "campaign_id", id)
+	return nil
+}
@@ -0,0 +1,125 @@
+// File: log.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log level of messages reporting a successfully finished operation
+const LevelOK = slog.LevelInfo + 2
+
+// ConsoleHandler is a slog.Handler that writes colored, human-readable lines
+// such as "[OK] Added subscriber. email=john@example.com". It is the default
+// handler of APIClient.
+type ConsoleHandler struct {
+	w      io.Writer
+	level  slog.Leveler
+	mutex  *sync.Mutex
+	attrs  []slog.Attr
+	prefix string
+}
+
+// Create a ConsoleHandler writing to w. If opts is nil, messages of level
+// slog.LevelInfo and above are written.
+func NewConsoleHandler(w io.Writer, opts *slog.HandlerOptions) *ConsoleHandler {
+	var level slog.Leveler = slog.LevelInfo
+	if opts != nil && opts.Level != nil {
+		level = opts.Level
+	}
+	return &ConsoleHandler{w: w, level: level, mutex: &sync.Mutex{}}
+}
+
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString("[" + levelLabel(r.Level) + "] " + r.Message)
+	for _, attr := range h.attrs {
+		writeAttr(&sb, "", attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&sb, h.prefix, attr)
+		return true
+	})
+	sb.WriteString("\n")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	_, err := io.WriteString(h.w, sb.String())
+	return err
+}
+
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	handler.attrs = append(handler.attrs, h.attrs...)
+	for _, attr := range attrs {
+		handler.attrs = append(handler.attrs, slog.Attr{Key: h.prefix + attr.Key, Value: attr.Value})
+	}
+	return &handler
+}
+
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.prefix = h.prefix + name + "."
+	return &handler
+}
+
+func levelLabel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return AnsiEscape["BoldRed"]("ERROR")
+	case level >= slog.LevelWarn:
+		return AnsiEscape["BoldYellow"]("WARNING")
+	case level >= LevelOK:
+		return AnsiEscape["BoldGreen"]("OK")
+	case level >= slog.LevelInfo:
+		return AnsiEscape["BoldCyan"]("INFO")
+	default:
+		return "DEBUG"
+	}
+}
+
+func writeAttr(sb *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, groupAttr := range attr.Value.Group() {
+			writeAttr(sb, prefix+attr.Key+".", groupAttr)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	sb.WriteString(" " + prefix + attr.Key + "=" + value)
+}
+
+func (c *APIClient) logInfo(ctx context.Context, msg string, args ...any) {
+	c.logger.Log(ctx, slog.LevelInfo, msg, args...)
+}
+
+// Log a successfully finished operation together with its duration
+func (c *APIClient) logOK(ctx context.Context, start time.Time, msg string, args ...any) {
+	c.logger.Log(ctx, LevelOK, msg, append(args, "duration", time.Since(start))...)
+}
+
+// Log an aborted operation together with its duration
+func (c *APIClient) logWarning(ctx context.Context, start time.Time, msg string, args ...any) {
+	c.logger.Log(ctx, slog.LevelWarn, msg, append(args, "duration", time.Since(start))...)
+}
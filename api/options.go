@@ -0,0 +1,126 @@
+// File: options.go
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Option configures an APIClient created with NewClient
+type Option func(*APIClient)
+
+// Use a custom HTTP client for all requests sent to Listmonk
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *APIClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// Set a time limit for every request sent to Listmonk
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *APIClient) {
+		c.timeout = timeout
+	}
+}
+
+// Do not fetch mailing lists when creating the client. The MailingListIDs
+// cache is loaded on first use instead, so the client can be created even if
+// Listmonk is unreachable.
+func WithLazyListLoading() Option {
+	return func(c *APIClient) {
+		c.lazyLists = true
+	}
+}
+
+// Create mailing lists that do not exist when subscribing to them instead of
+// returning an error
+func WithAutoCreateLists() Option {
+	return func(c *APIClient) {
+		c.autoCreateLists = true
+	}
+}
+
+// Send log messages to the given structured logger instead of writing them to
+// standard output with ConsoleHandler. Use slog.New(slog.NewJSONHandler(...))
+// for JSON logs or a logger with a discarding handler to silence the client.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *APIClient) {
+		c.logger = logger
+	}
+}
+
+// Choose whether email addresses that are not normalized (see NormalizeEmail)
+// are fixed or rejected when creating subscribers. They are fixed by default.
+func WithEmailPolicy(policy EmailPolicy) Option {
+	return func(c *APIClient) {
+		c.emailPolicy = policy
+	}
+}
+
+// Reject email addresses of domains listed in a file, one domain per line.
+// Subdomains of listed domains are rejected as well. NewClient returns an
+// error if the file cannot be read.
+func WithDisposableDomains(path string) Option {
+	return func(c *APIClient) {
+		c.disposableDomainsFile = path
+	}
+}
+
+// Record who received each campaign in ledger. Resumed campaigns are then sent
+// to all recipients missing from the ledger instead of to subscribers who
+// joined the campaign's lists after its latest send.
+func WithDeliveryLedger(ledger DeliveryLedger) Option {
+	return func(c *APIClient) {
+		c.ledger = ledger
+	}
+}
+
+// Send test emails of campaigns to these addresses when SendCampaignTest is
+// called without recipients. Reviewers must be subscribers in Listmonk.
+func WithReviewers(emails ...string) Option {
+	return func(c *APIClient) {
+		c.reviewers = emails
+	}
+}
+
+// Refuse to launch a campaign for the first time unless it was sent as a test
+// with SendCampaignTest after its last change, by any client
+func WithRequiredTestSend() Option {
+	return func(c *APIClient) {
+		c.requireTestSend = true
+	}
+}
+
+// Send campaigns and transactional emails with sender instead of from
+// newsletter@3mdeb.com through the "email" messenger. Empty fields of sender
+// keep their defaults. NewClient returns an error matching ErrInvalidEmail if
+// an address is invalid.
+func WithSender(sender Sender) Option {
+	return func(c *APIClient) {
+		c.sender = c.sender.with(sender)
+	}
+}
+
+// Send transactional emails of a subscription type with sender. Empty fields
+// of sender are taken from the sender set with WithSender. Subscription types
+// are list names, so campaigns created with CreateCampaignHTMLOnListName or
+// launched with LaunchCampaignListName on that list use sender as well.
+func WithSubscriptionSender(subscriptionType string, sender Sender) Option {
+	return func(c *APIClient) {
+		if c.subscriptionSenders == nil {
+			c.subscriptionSenders = map[string]Sender{}
+		}
+		c.subscriptionSenders[subscriptionType] = sender
+	}
+}
+
+// Do not send any writes to Listmonk. Reads (lookups, detection of new
+// subscribers for incremental launches) are performed as usual, while
+// subscribers, lists, campaigns and emails that would be created, changed,
+// launched or sent are recorded in plan instead.
+func WithDryRun(plan *Plan) Option {
+	return func(c *APIClient) {
+		c.plan = plan
+	}
+}
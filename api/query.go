@@ -0,0 +1,221 @@
+// File: query.go
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format of timestamps in subscriber queries
+const queryTimeFormat = "2006-01-02T15:04:05.999999-07:00"
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Query is a Listmonk subscriber query expression (an SQL condition on the
+// subscribers table). Build queries with the functions in this file instead of
+// formatting SQL by hand, so that all values are escaped.
+type Query struct {
+	sql string
+	err error
+}
+
+// Field is the left-hand side of a comparison: a column or an attribute.
+type Field struct {
+	sql string
+	err error
+}
+
+// Return the SQL expression of the query or the first error encountered while
+// building it
+func (q Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	return q.sql, nil
+}
+
+// Reference a column, e.g. "subscribers.email" or "created_at"
+func Column(name string) Field {
+	if !identifierRegexp.MatchString(name) {
+		return Field{err: fmt.Errorf("invalid column name: %q", name)}
+	}
+	return Field{sql: name}
+}
+
+// Reference a subscriber attribute. Nested attributes are given as a path,
+// e.g. Attr("preferences", "newsletter"). The value is compared as text.
+func Attr(path ...string) Field {
+	if len(path) == 0 {
+		return Field{err: fmt.Errorf("empty attribute path")}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("subscribers.attribs")
+	for i, key := range path {
+		literal, err := quoteLiteral(key)
+		if err != nil {
+			return Field{err: err}
+		}
+		if i == len(path)-1 {
+			sb.WriteString("->>")
+		} else {
+			sb.WriteString("->")
+		}
+		sb.WriteString(literal)
+	}
+	return Field{sql: sb.String()}
+}
+
+// Compare the field case-insensitively
+func Lower(f Field) Field {
+	if f.err != nil {
+		return f
+	}
+	return Field{sql: "LOWER(" + f.sql + ")"}
+}
+
+// Match if field = value
+func Eq(f Field, value interface{}) Query {
+	return compare(f, "=", value)
+}
+
+// Match if field <> value
+func Neq(f Field, value interface{}) Query {
+	return compare(f, "<>", value)
+}
+
+// Match if field > value
+func Gt(f Field, value interface{}) Query {
+	return compare(f, ">", value)
+}
+
+// Match if field >= value
+func Gte(f Field, value interface{}) Query {
+	return compare(f, ">=", value)
+}
+
+// Match if field < value
+func Lt(f Field, value interface{}) Query {
+	return compare(f, "<", value)
+}
+
+// Match if field <= value
+func Lte(f Field, value interface{}) Query {
+	return compare(f, "<=", value)
+}
+
+// Match if field IN (values...). An empty list of values matches nothing.
+func In(f Field, values ...interface{}) Query {
+	if f.err != nil {
+		return Query{err: f.err}
+	}
+	if len(values) == 0 {
+		return Query{sql: "FALSE"}
+	}
+
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literal, err := formatValue(value)
+		if err != nil {
+			return Query{err: err}
+		}
+		literals[i] = literal
+	}
+	return Query{sql: fmt.Sprintf("%s IN (%s)", f.sql, strings.Join(literals, ","))}
+}
+
+// Match if field IN (SELECT column FROM table WHERE where)
+func InSubquery(f Field, table, column string, where Query) Query {
+	if f.err != nil {
+		return Query{err: f.err}
+	}
+	if where.err != nil {
+		return where
+	}
+	if !identifierRegexp.MatchString(table) {
+		return Query{err: fmt.Errorf("invalid table name: %q", table)}
+	}
+	if !identifierRegexp.MatchString(column) {
+		return Query{err: fmt.Errorf("invalid column name: %q", column)}
+	}
+	return Query{sql: fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)", f.sql, column, table, where.sql)}
+}
+
+// Match if all queries match. No queries match everything.
+func And(queries ...Query) Query {
+	return join("AND", "TRUE", queries)
+}
+
+// Match if any query matches. No queries match nothing.
+func Or(queries ...Query) Query {
+	return join("OR", "FALSE", queries)
+}
+
+// Match if the query does not match
+func Not(q Query) Query {
+	if q.err != nil {
+		return q
+	}
+	return Query{sql: "NOT (" + q.sql + ")"}
+}
+
+func compare(f Field, operator string, value interface{}) Query {
+	if f.err != nil {
+		return Query{err: f.err}
+	}
+	literal, err := formatValue(value)
+	if err != nil {
+		return Query{err: err}
+	}
+	return Query{sql: fmt.Sprintf("%s %s %s", f.sql, operator, literal)}
+}
+
+func join(operator, empty string, queries []Query) Query {
+	if len(queries) == 0 {
+		return Query{sql: empty}
+	}
+
+	parts := make([]string, len(queries))
+	for i, q := range queries {
+		if q.err != nil {
+			return q
+		}
+		parts[i] = "(" + q.sql + ")"
+	}
+	return Query{sql: strings.Join(parts, " "+operator+" ")}
+}
+
+// Format a Go value as an SQL literal
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quoteLiteral(v)
+	case time.Time:
+		return quoteLiteral(v.Format(queryTimeFormat))
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported query value type: %T", value)
+	}
+}
+
+// Quote a string as an SQL literal, escaping single quotes
+func quoteLiteral(s string) (string, error) {
+	if strings.ContainsRune(s, 0) {
+		return "", fmt.Errorf("query value contains a NUL character")
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
@@ -0,0 +1,126 @@
+// File: report.go
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// Outcome of importing a single row
+type RowStatus string
+
+const (
+	RowCreated RowStatus = "created"
+	RowUpdated RowStatus = "updated"
+	// Nothing to do: the subscriber is up to date or the row is empty
+	RowSkipped RowStatus = "skipped"
+	RowFailed  RowStatus = "failed"
+)
+
+// Result of importing a single row
+type RowResult struct {
+	// Line of the row in the imported file, or position of the entry
+	// (starting at 1) in a JSON array
+	Line         int       `json:"line"`
+	Email        string    `json:"email"`
+	Status       RowStatus `json:"status"`
+	SubscriberID uint      `json:"subscriber_id,omitempty"`
+	// Why the row was skipped or failed
+	Reason string `json:"reason,omitempty"`
+	// Fields of the row as read from the file. Entries of JSON imports are
+	// stored as a single field with the JSON object.
+	Record []string `json:"record"`
+}
+
+// ImportReport lists the outcome of every imported row
+type ImportReport struct {
+	// Header row of the imported file
+	Header []string    `json:"header"`
+	Rows   []RowResult `json:"rows"`
+}
+
+// Return the number of rows with given status
+func (r *ImportReport) Count(status RowStatus) int {
+	count := 0
+	for _, row := range r.Rows {
+		if row.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Return rows that failed to import
+func (r *ImportReport) Failed() []RowResult {
+	var failed []RowResult
+	for _, row := range r.Rows {
+		if row.Status == RowFailed {
+			failed = append(failed, row)
+		}
+	}
+	return failed
+}
+
+// Write the report as CSV with line, email, status, subscriber_id and reason
+// columns
+func (r *ImportReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"line", "email", "status", "subscriber_id", "reason"})
+	if err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		id := ""
+		if row.SubscriberID != 0 {
+			id = strconv.FormatUint(uint64(row.SubscriberID), 10)
+		}
+		err = writer.Write([]string{strconv.Itoa(row.Line), row.Email, string(row.Status), id, row.Reason})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the report as indented JSON
+func (r *ImportReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Write the header and the rows that failed to import as CSV, so that the
+// file can be fixed and imported again
+func (r *ImportReport) WriteFailedCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(r.Header)
+	if err != nil {
+		return err
+	}
+	for _, row := range r.Failed() {
+		err = writer.Write(row.Record)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the JSON objects of entries that failed to import as JSON Lines, so
+// that they can be fixed and imported again with AddSubscribersFromJSONL
+func (r *ImportReport) WriteFailedJSONL(w io.Writer) error {
+	for _, row := range r.Failed() {
+		if len(row.Record) == 0 {
+			continue
+		}
+		_, err := io.WriteString(w, row.Record[0]+"\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
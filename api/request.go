@@ -0,0 +1,80 @@
+// File: request.go
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Send a JSON request to a Listmonk endpoint that go-listmonk does not cover
+// (or covers incorrectly) and decode the "data" field of the response into
+// out. Both body and out may be nil.
+func (c *APIClient) callAPI(ctx context.Context, method, endpoint string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	return c.callAPIRaw(ctx, method, endpoint, "application/json", reader, out)
+}
+
+// Send a request with an arbitrary body to a Listmonk endpoint. Errors
+// returned by Listmonk are reported as *APIError.
+func (c *APIClient) callAPIRaw(ctx context.Context, method, endpoint, contentType string, body io.Reader, out interface{}) error {
+	url := fmt.Sprintf("%s/api/%s", c.BaseURL, strings.TrimPrefix(endpoint, "/"))
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Username != nil && c.Password != nil {
+		req.SetBasicAuth(*c.Username, *c.Password)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: res.StatusCode}
+		var result struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &result) == nil && result.Message != "" {
+			apiErr.Message = result.Message
+		} else {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(result.Data, out)
+}
@@ -0,0 +1,182 @@
+// File: schedule.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Create a new campaign with content of given type and schedule it to be sent
+// at sendAt. Fails with ErrTestSendRequired with WithRequiredTestSend, as the
+// campaign cannot have been sent as a test.
+func (c *APIClient) CreateScheduledCampaign(name, subject string, lists []uint, content, contentType string, sendAt time.Time) (uint, error) {
+	return c.CreateScheduledCampaignContext(context.Background(), name, subject, lists, content, contentType, sendAt)
+}
+
+// CreateScheduledCampaignContext is like CreateScheduledCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) CreateScheduledCampaignContext(ctx context.Context, name, subject string, lists []uint, content, contentType string, sendAt time.Time) (uint, error) {
+	err := checkSendAt(sendAt)
+	if err != nil {
+		return 0, err
+	}
+	if c.requireTestSend {
+		// A new campaign cannot have been sent as a test
+		return 0, fmt.Errorf("%w: %s, create it, send it as a test and then schedule it with ScheduleCampaign", ErrTestSendRequired, name)
+	}
+	id, err := c.CreateCampaignContext(ctx, name, subject, lists, content, contentType)
+	if err != nil {
+		return 0, err
+	}
+	if c.plan != nil {
+		// The campaign was not created, so there is nothing to fetch
+		return id, c.scheduleCampaign(ctx, &listmonk.Campaign{Name: name, Status: "draft"}, sendAt)
+	}
+	return id, c.ScheduleCampaignContext(ctx, id, sendAt)
+}
+
+// Schedule a draft campaign to be sent at sendAt. Listmonk starts sending it
+// at that time, LaunchCampaign is not needed. Already scheduled campaigns are
+// rescheduled. With WithRequiredTestSend, the campaign must have been sent as a
+// test after its last change, as for LaunchCampaign.
+func (c *APIClient) ScheduleCampaign(id uint, sendAt time.Time) error {
+	return c.ScheduleCampaignContext(context.Background(), id, sendAt)
+}
+
+// ScheduleCampaignContext is like ScheduleCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) ScheduleCampaignContext(ctx context.Context, id uint, sendAt time.Time) error {
+	err := checkSendAt(sendAt)
+	if err != nil {
+		return err
+	}
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+	if campaign.Status != "draft" && campaign.Status != "scheduled" {
+		return fmt.Errorf("%w: campaign %d is %s, only draft and scheduled campaigns can be scheduled", ErrValidation, id, campaign.Status)
+	}
+	return c.scheduleCampaign(ctx, campaign, sendAt)
+}
+
+// Move a scheduled campaign to a new send time
+func (c *APIClient) RescheduleCampaign(id uint, sendAt time.Time) error {
+	return c.RescheduleCampaignContext(context.Background(), id, sendAt)
+}
+
+// RescheduleCampaignContext is like RescheduleCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) RescheduleCampaignContext(ctx context.Context, id uint, sendAt time.Time) error {
+	err := checkSendAt(sendAt)
+	if err != nil {
+		return err
+	}
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+	if campaign.Status != "scheduled" {
+		return fmt.Errorf("%w: campaign %d is %s, not scheduled", ErrValidation, id, campaign.Status)
+	}
+	return c.scheduleCampaign(ctx, campaign, sendAt)
+}
+
+// Cancel the schedule of a campaign and turn it back into a draft. Its send
+// time is cleared.
+func (c *APIClient) UnscheduleCampaign(id uint) error {
+	return c.UnscheduleCampaignContext(context.Background(), id)
+}
+
+// UnscheduleCampaignContext is like UnscheduleCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) UnscheduleCampaignContext(ctx context.Context, id uint) error {
+	start := time.Now()
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+	if campaign.Status != "scheduled" {
+		return fmt.Errorf("%w: campaign %d is %s, not scheduled", ErrValidation, id, campaign.Status)
+	}
+
+	c.logInfo(ctx, "Unscheduling campaign.", "campaign_id", id)
+	err = c.setCampaignStatus(ctx, campaign, "draft")
+	if err != nil {
+		return err
+	}
+	_, err = c.updateCampaign(ctx, campaign, map[string]interface{}{"send_later": false, "send_at": nil})
+	if err != nil {
+		return err
+	}
+	c.logOK(ctx, start, "Unscheduled campaign.", "campaign", campaign.Name, "campaign_id", id)
+	return nil
+}
+
+// List scheduled campaigns, the earliest send time first
+func (c *APIClient) ScheduledCampaigns() ([]*listmonk.Campaign, error) {
+	return c.ScheduledCampaignsContext(context.Background())
+}
+
+// ScheduledCampaignsContext is like ScheduledCampaigns but uses ctx for all Listmonk requests.
+func (c *APIClient) ScheduledCampaignsContext(ctx context.Context) ([]*listmonk.Campaign, error) {
+	getCampaignsService := c.Client.NewGetCampaignsService()
+	getCampaignsService.PerPage("all")
+	campaigns, err := apiResult(getCampaignsService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []*listmonk.Campaign
+	for _, campaign := range campaigns {
+		if campaign.Status == "scheduled" {
+			scheduled = append(scheduled, campaign)
+		}
+	}
+	sort.Slice(scheduled, func(i, j int) bool {
+		if scheduled[i].SendAt.Equal(scheduled[j].SendAt) {
+			return scheduled[i].Id < scheduled[j].Id
+		}
+		return scheduled[i].SendAt.Before(scheduled[j].SendAt)
+	})
+	return scheduled, nil
+}
+
+// Listmonk only accepts send times in the future
+func checkSendAt(sendAt time.Time) error {
+	if !sendAt.After(time.Now()) {
+		return fmt.Errorf("%w: send time %s is not in the future", ErrValidation, sendAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Set the send time of a draft or scheduled campaign and mark it scheduled.
+// Listmonk does not allow editing scheduled campaigns, so they are turned
+// back into drafts first.
+func (c *APIClient) scheduleCampaign(ctx context.Context, campaign *listmonk.Campaign, sendAt time.Time) error {
+	start := time.Now()
+	if c.requireTestSend {
+		err := c.checkTestSent(ctx, campaign)
+		if err != nil {
+			return err
+		}
+	}
+	c.logInfo(ctx, "Scheduling campaign.", "campaign_id", campaign.Id, "send_at", sendAt)
+	if campaign.Status == "scheduled" {
+		err := c.setCampaignStatus(ctx, campaign, "draft")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := c.updateCampaign(ctx, campaign, map[string]interface{}{"send_later": true, "send_at": sendAt})
+	if err != nil {
+		return err
+	}
+	err = c.setCampaignStatus(ctx, campaign, "scheduled")
+	if err != nil {
+		return err
+	}
+	c.logOK(ctx, start, "Scheduled campaign.", "campaign", campaign.Name, "campaign_id", campaign.Id, "send_at", sendAt)
+	return nil
+}
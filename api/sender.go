@@ -0,0 +1,142 @@
+// File: sender.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"slices"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Identity campaigns and transactional emails are sent with. Empty fields are
+// taken from the client's defaults.
+type Sender struct {
+	// From address, optionally with a display name, e.g.
+	// "3mdeb <newsletter@3mdeb.com>"
+	FromEmail string
+	// Address replies go to, the from address if empty
+	ReplyTo string
+	// Listmonk messenger delivering the messages, e.g. "email"
+	Messenger string
+}
+
+// Sender used unless configured otherwise with WithSender
+var defaultSender = Sender{FromEmail: "newsletter@3mdeb.com", Messenger: "email"}
+
+// Return s with fields replaced by the non-empty fields of override
+func (s Sender) with(override Sender) Sender {
+	if override.FromEmail != "" {
+		s.FromEmail = override.FromEmail
+	}
+	if override.ReplyTo != "" {
+		s.ReplyTo = override.ReplyTo
+	}
+	if override.Messenger != "" {
+		s.Messenger = override.Messenger
+	}
+	return s
+}
+
+// Check that the addresses of s are valid RFC 5322 addresses
+func (s Sender) validate() error {
+	_, err := mail.ParseAddress(s.FromEmail)
+	if err != nil {
+		return fmt.Errorf("%w: from address %q: %w", ErrInvalidEmail, s.FromEmail, err)
+	}
+	if s.ReplyTo != "" {
+		_, err = mail.ParseAddress(s.ReplyTo)
+		if err != nil {
+			return fmt.Errorf("%w: reply-to address %q: %w", ErrInvalidEmail, s.ReplyTo, err)
+		}
+	}
+	return nil
+}
+
+// Extra headers of messages sent by s, in the format used by Listmonk
+func (s Sender) headers() []map[string]string {
+	if s.ReplyTo == "" {
+		return nil
+	}
+	return []map[string]string{{"Reply-To": s.ReplyTo}}
+}
+
+// Get the sender for a message: the client's sender overridden by the sender
+// configured for subscriptionType (if not empty), overridden by override
+func (c *APIClient) resolveSender(subscriptionType string, override Sender) (Sender, error) {
+	sender := c.sender
+	if subscriptionType != "" {
+		sender = sender.with(c.subscriptionSenders[subscriptionType])
+	}
+	sender = sender.with(override)
+	err := sender.validate()
+	if err != nil {
+		return Sender{}, err
+	}
+	return sender, nil
+}
+
+// Get the extra headers of a campaign, which go-listmonk does not decode.
+// Listmonk drops headers that are not sent on update, so they have to be
+// copied explicitly.
+func (c *APIClient) getCampaignHeaders(ctx context.Context, campaign *listmonk.Campaign) ([]map[string]string, error) {
+	headers := []map[string]string{}
+	if campaign.Id == 0 {
+		// The campaign was not created in dry-run mode
+		return headers, nil
+	}
+	var data struct {
+		Headers []map[string]string `json:"headers"`
+	}
+	err := c.callAPI(ctx, http.MethodGet, fmt.Sprintf("/campaigns/%d", campaign.Id), nil, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Headers != nil {
+		headers = data.Headers
+	}
+	return headers, nil
+}
+
+// Switch a draft campaign to the sender configured for subscriptionType. Only
+// campaigns still using the client's sender are changed, so a sender given
+// when creating the campaign is kept. Campaigns sent as a test with their
+// current content are not changed either, so that customers get the messages
+// reviewers approved.
+func (c *APIClient) applySubscriptionSender(ctx context.Context, campaign *listmonk.Campaign, subscriptionType string) (*listmonk.Campaign, error) {
+	start := time.Now()
+	if _, ok := c.subscriptionSenders[subscriptionType]; !ok || campaign.Status != "draft" {
+		return campaign, nil
+	}
+	if campaign.FromEmail != c.sender.FromEmail || campaign.Messenger != c.sender.Messenger {
+		return campaign, nil
+	}
+	if c.checkTestSent(ctx, campaign) == nil {
+		c.logWarning(ctx, start, "Campaign was tested with the client's sender, not using sender of subscription type.", "campaign_id", campaign.Id, "subscription_type", subscriptionType)
+		return campaign, nil
+	}
+	sender, err := c.resolveSender(subscriptionType, Sender{})
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := c.getCampaignHeaders(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	headers = slices.DeleteFunc(headers, func(header map[string]string) bool {
+		_, ok := header["Reply-To"]
+		return ok
+	})
+	headers = append(headers, sender.headers()...)
+
+	c.logInfo(ctx, "Using sender of subscription type.", "campaign_id", campaign.Id, "subscription_type", subscriptionType, "from_email", sender.FromEmail)
+	return c.updateCampaign(ctx, campaign, map[string]interface{}{
+		"from_email": sender.FromEmail,
+		"messenger":  sender.Messenger,
+		"headers":    headers,
+	})
+}
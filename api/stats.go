@@ -0,0 +1,228 @@
+// File: stats.go
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statistics of a campaign. For campaigns that were resumed, the counts are
+// totals of the original launch and all incremental sends, which are listed
+// with their own counts in Incremental.
+type CampaignStats struct {
+	CampaignID  uint            `json:"campaign_id"`
+	Name        string          `json:"name"`
+	Status      string          `json:"status"`
+	StartedAt   time.Time       `json:"started_at"`
+	ToSend      uint            `json:"to_send"`
+	Sent        uint            `json:"sent"`
+	Views       uint            `json:"views"`
+	Clicks      uint            `json:"clicks"`
+	Bounces     uint            `json:"bounces"`
+	Incremental []CampaignStats `json:"incremental,omitempty"`
+}
+
+// Statistics of campaigns, e.g. for a monthly report
+type StatsReport struct {
+	Campaigns []CampaignStats `json:"campaigns"`
+}
+
+// Campaign fields with statistics as returned by Listmonk. go-listmonk does
+// not decode views and bounces.
+type campaignData struct {
+	Id        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	StartedAt time.Time `json:"started_at"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Tags      []string  `json:"tags"`
+	ToSend    uint      `json:"to_send"`
+	Sent      uint      `json:"sent"`
+	Views     uint      `json:"views"`
+	Clicks    uint      `json:"clicks"`
+	Bounces   uint      `json:"bounces"`
+}
+
+// Get statistics of a campaign, including all its incremental sends
+func (c *APIClient) GetCampaignStats(id uint) (*CampaignStats, error) {
+	return c.GetCampaignStatsContext(context.Background(), id)
+}
+
+// GetCampaignStatsContext is like GetCampaignStats but uses ctx for all Listmonk requests.
+func (c *APIClient) GetCampaignStatsContext(ctx context.Context, id uint) (*CampaignStats, error) {
+	campaigns, err := c.getCampaignsData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, stats := range groupCampaignStats(campaigns) {
+		if stats.CampaignID == id {
+			return &stats, nil
+		}
+		for _, inc := range stats.Incremental {
+			if inc.CampaignID == id {
+				return &inc, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrCampaignNotFound, id)
+}
+
+// Get statistics of all campaigns first launched in [since, until), oldest
+// first. Incremental sends are counted with their original campaign, even if
+// they were sent later.
+func (c *APIClient) GetStatsReport(since, until time.Time) (*StatsReport, error) {
+	return c.GetStatsReportContext(context.Background(), since, until)
+}
+
+// GetStatsReportContext is like GetStatsReport but uses ctx for all Listmonk requests.
+func (c *APIClient) GetStatsReportContext(ctx context.Context, since, until time.Time) (*StatsReport, error) {
+	start := time.Now()
+	campaigns, err := c.getCampaignsData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatsReport{}
+	for _, stats := range groupCampaignStats(campaigns) {
+		if stats.StartedAt.IsZero() || stats.StartedAt.Before(since) || !stats.StartedAt.Before(until) {
+			continue
+		}
+		report.Campaigns = append(report.Campaigns, stats)
+	}
+	sort.Slice(report.Campaigns, func(i, j int) bool {
+		return report.Campaigns[i].StartedAt.Before(report.Campaigns[j].StartedAt)
+	})
+	c.logOK(ctx, start, "Collected campaign statistics.", "since", since, "until", until, "campaigns", len(report.Campaigns))
+	return report, nil
+}
+
+// Fetch all campaigns with their statistics
+func (c *APIClient) getCampaignsData(ctx context.Context) ([]campaignData, error) {
+	var result struct {
+		Results []campaignData `json:"results"`
+	}
+	err := c.callAPI(ctx, http.MethodGet, "/campaigns?per_page=all", nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// Group incremental campaigns under their original campaigns and sum up their
+// counts. Incremental campaigns are matched only by their incremental_of_<id>
+// tag; untagged campaigns and those whose original was deleted are reported on
+// their own.
+func groupCampaignStats(campaigns []campaignData) []CampaignStats {
+	byID := map[uint]int{}
+	for i, campaign := range campaigns {
+		byID[campaign.Id] = i
+	}
+
+	parentOf := func(campaign campaignData) (int, bool) {
+		for _, tag := range campaign.Tags {
+			idText, ok := strings.CutPrefix(tag, incrementalTagPrefix)
+			if !ok {
+				continue
+			}
+			id, err := strconv.ParseUint(idText, 10, 0)
+			if err != nil {
+				continue
+			}
+			if i, ok := byID[uint(id)]; ok {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	incremental := map[int][]int{}
+	var originals []int
+	for i, campaign := range campaigns {
+		if parent, ok := parentOf(campaign); ok && parent != i {
+			incremental[parent] = append(incremental[parent], i)
+		} else {
+			originals = append(originals, i)
+		}
+	}
+
+	stats := make([]CampaignStats, 0, len(originals))
+	for _, i := range originals {
+		total := campaignStats(campaigns[i])
+		incs := incremental[i]
+		sort.Slice(incs, func(a, b int) bool {
+			return campaigns[incs[a]].CreatedAt.Before(campaigns[incs[b]].CreatedAt)
+		})
+		for _, inc := range incs {
+			incStats := campaignStats(campaigns[inc])
+			total.ToSend += incStats.ToSend
+			total.Sent += incStats.Sent
+			total.Views += incStats.Views
+			total.Clicks += incStats.Clicks
+			total.Bounces += incStats.Bounces
+			total.Incremental = append(total.Incremental, incStats)
+		}
+		stats = append(stats, total)
+	}
+	return stats
+}
+
+func campaignStats(campaign campaignData) CampaignStats {
+	return CampaignStats{
+		CampaignID: campaign.Id,
+		Name:       campaign.Name,
+		Status:     campaign.Status,
+		StartedAt:  campaign.StartedAt,
+		ToSend:     campaign.ToSend,
+		Sent:       campaign.Sent,
+		Views:      campaign.Views,
+		Clicks:     campaign.Clicks,
+		Bounces:    campaign.Bounces,
+	}
+}
+
+// Write one line per campaign with its total counts as CSV
+func (r *StatsReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"campaign_id", "name", "status", "started_at", "sends", "to_send", "sent", "views", "clicks", "bounces"})
+	if err != nil {
+		return err
+	}
+	for _, stats := range r.Campaigns {
+		startedAt := ""
+		if !stats.StartedAt.IsZero() {
+			startedAt = stats.StartedAt.Format(time.RFC3339)
+		}
+		err = writer.Write([]string{
+			strconv.FormatUint(uint64(stats.CampaignID), 10),
+			stats.Name,
+			stats.Status,
+			startedAt,
+			strconv.Itoa(1 + len(stats.Incremental)),
+			strconv.FormatUint(uint64(stats.ToSend), 10),
+			strconv.FormatUint(uint64(stats.Sent), 10),
+			strconv.FormatUint(uint64(stats.Views), 10),
+			strconv.FormatUint(uint64(stats.Clicks), 10),
+			strconv.FormatUint(uint64(stats.Bounces), 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Write the report as indented JSON, including incremental sends
+func (r *StatsReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
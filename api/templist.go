@@ -0,0 +1,103 @@
+// File: templist.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Prefix of temporary lists used to send incremental campaigns
+const tempListPrefix = "temp_list"
+
+// Temporary lists older than this that no campaign targets are considered left
+// behind by crashed resumes and are removed before the next resume
+const tempListMaxAge = 24 * time.Hour
+
+// Unique name of a temporary list for an incremental send of campaign
+func tempListName(campaign *listmonk.Campaign) string {
+	return fmt.Sprintf("%s_%d_%d", tempListPrefix, campaign.Id, time.Now().UnixNano())
+}
+
+// Check if a list is a temporary list, including the fixed-name lists of
+// older versions
+func isTempList(name string) bool {
+	return name == tempListPrefix || strings.HasPrefix(name, tempListPrefix+"_")
+}
+
+// Delete temporary lists that are no longer needed: lists of incremental
+// campaigns that are finished or cancelled, and lists created more than maxAge
+// ago that no campaign targets, left behind by resumes that crashed before
+// launching. Lists of campaigns that may still send (e.g. paused ones) are
+// kept. Returns names of deleted lists.
+func (c *APIClient) CleanupTempLists(maxAge time.Duration) ([]string, error) {
+	return c.CleanupTempListsContext(context.Background(), maxAge)
+}
+
+// CleanupTempListsContext is like CleanupTempLists but uses ctx for all Listmonk requests.
+func (c *APIClient) CleanupTempListsContext(ctx context.Context, maxAge time.Duration) ([]string, error) {
+	return c.cleanupTempLists(ctx, maxAge, isTempList)
+}
+
+// Delete temporary lists that are no longer needed, considering only lists
+// whose names match
+func (c *APIClient) cleanupTempLists(ctx context.Context, maxAge time.Duration, match func(name string) bool) ([]string, error) {
+	getListsService := c.Client.NewGetListsService()
+	getListsService.Query(tempListPrefix)
+	getListsService.PerPage("all")
+	lists, err := apiResult(getListsService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	getCampaignsService := c.Client.NewGetCampaignsService()
+	getCampaignsService.PerPage("all")
+	campaigns, err := apiResult(getCampaignsService.Do(ctx))
+	if err != nil {
+		return nil, err
+	}
+	// Whether all campaigns targeting a list are done with it
+	listDone := map[uint]bool{}
+	for _, campaign := range campaigns {
+		done := campaign.Status == "finished" || campaign.Status == "cancelled"
+		for _, list := range campaign.Lists {
+			previous, ok := listDone[list.Id]
+			listDone[list.Id] = done && (previous || !ok)
+		}
+	}
+
+	var deleted []string
+	for _, list := range lists {
+		if !match(list.Name) {
+			continue
+		}
+		done, targeted := listDone[list.Id]
+		if targeted && !done || !targeted && time.Since(list.CreatedAt) < maxAge {
+			continue
+		}
+		c.logInfo(ctx, "Deleting temporary list.", "list", list.Name, "created_at", list.CreatedAt)
+		err = c.deleteListID(ctx, list.Id, list.Name)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, list.Name)
+	}
+	return deleted, nil
+}
+
+// Remove temporary lists that are no longer needed before a resume. Failures
+// are only logged, as they do not prevent the resume.
+func (c *APIClient) cleanupOrphanedTempLists(ctx context.Context) {
+	start := time.Now()
+	deleted, err := c.CleanupTempListsContext(ctx, tempListMaxAge)
+	if err != nil {
+		c.logWarning(ctx, start, "Could not clean up temporary lists.", "error", err)
+		return
+	}
+	if len(deleted) > 0 {
+		c.logOK(ctx, start, "Cleaned up temporary lists.", "lists", deleted)
+	}
+}
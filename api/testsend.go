@@ -0,0 +1,119 @@
+// File: testsend.go
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	listmonk "github.com/Exayn/go-listmonk"
+)
+
+// Send a campaign as a test to reviewers, rendered as it would be for them.
+// The reviewers configured with WithReviewers are used if emails is empty.
+// Every reviewer must be a subscriber in Listmonk, but does not have to be on
+// the lists of the campaign. The test of a draft is recorded in a
+// tested_<hash> tag of the campaign, which WithRequiredTestSend checks.
+func (c *APIClient) SendCampaignTest(id uint, emails []string) error {
+	return c.SendCampaignTestContext(context.Background(), id, emails)
+}
+
+// SendCampaignTestContext is like SendCampaignTest but uses ctx for all Listmonk requests.
+func (c *APIClient) SendCampaignTestContext(ctx context.Context, id uint, emails []string) error {
+	start := time.Now()
+	if len(emails) == 0 {
+		emails = c.reviewers
+	}
+	if len(emails) == 0 {
+		return fmt.Errorf("%w: no reviewers to send the test to", ErrValidation)
+	}
+	reviewers := make([]string, len(emails))
+	for i, email := range emails {
+		normalized, err := NormalizeEmail(email)
+		if err != nil {
+			return err
+		}
+		reviewers[i] = normalized
+	}
+
+	campaign, err := c.getCampaign(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	headers, err := c.getCampaignHeaders(ctx, campaign)
+	if err != nil {
+		return err
+	}
+	body := campaignFields(campaign)
+	body["headers"] = headers
+	body["subscribers"] = reviewers
+	c.logInfo(ctx, "Sending test of campaign.", "campaign_id", id, "reviewers", reviewers)
+	if !c.dryRun(ctx, "Send test of campaign "+campaign.Name, http.MethodPost, fmt.Sprintf("/campaigns/%d/test", id), body) {
+		err = c.callAPI(ctx, http.MethodPost, fmt.Sprintf("/campaigns/%d/test", id), body, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the test in the campaign, so that other clients can check it.
+	// Listmonk only allows changing drafts, and the test of a campaign that
+	// is already scheduled or sending is not needed for launching it.
+	tag := testedTag(campaign, headers)
+	if campaign.Status != "draft" {
+		c.logInfo(ctx, "Not recording test of campaign that is not a draft.", "campaign_id", id, "status", campaign.Status)
+	} else if !slices.Contains(campaign.Tags, tag) {
+		tags := slices.DeleteFunc(slices.Clone(campaign.Tags), isTestedTag)
+		_, err = c.updateCampaign(ctx, campaign, map[string]interface{}{"tags": append(tags, tag)})
+		if err != nil {
+			return err
+		}
+	}
+	c.logOK(ctx, start, "Sent test of campaign.", "campaign", campaign.Name, "campaign_id", id, "reviewers", len(reviewers))
+	return nil
+}
+
+// Prefix of the tag recording the content a campaign was sent as a test with
+const testedTagPrefix = "tested_"
+
+// Check if tag records a test send
+func isTestedTag(tag string) bool {
+	return strings.HasPrefix(tag, testedTagPrefix)
+}
+
+// Tag recording a test send of campaign with its current content: a hash of
+// everything that ends up in the sent messages
+func testedTag(campaign *listmonk.Campaign, headers []map[string]string) string {
+	fields := campaignFields(campaign)
+	for _, key := range []string{"name", "tags", "send_later", "send_at"} {
+		delete(fields, key)
+	}
+	fields["headers"] = headers
+	lists := fields["lists"].([]uint)
+	slices.Sort(lists)
+	// Map keys are sorted, so the encoding is stable
+	data, _ := json.Marshal(fields)
+	sum := sha256.Sum256(data)
+	return testedTagPrefix + hex.EncodeToString(sum[:8])
+}
+
+// Check that campaign was sent as a test and has not changed since then
+func (c *APIClient) checkTestSent(ctx context.Context, campaign *listmonk.Campaign) error {
+	if !slices.ContainsFunc(campaign.Tags, isTestedTag) {
+		return fmt.Errorf("%w: %s", ErrTestSendRequired, campaign.Name)
+	}
+	headers, err := c.getCampaignHeaders(ctx, campaign)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(campaign.Tags, testedTag(campaign, headers)) {
+		return fmt.Errorf("%w: %s changed after its test", ErrTestSendRequired, campaign.Name)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// File: wait.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Progress of a launched campaign
+type CampaignProgress struct {
+	CampaignID uint   `json:"campaign_id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Sent       uint   `json:"sent"`
+	ToSend     uint   `json:"to_send"`
+}
+
+// Check if a campaign in this status will not send any more messages without
+// being resumed
+func (p CampaignProgress) Done() bool {
+	switch p.Status {
+	case "finished", "paused", "cancelled":
+		return true
+	}
+	return false
+}
+
+// Options of WaitForCampaign
+type WaitOptions struct {
+	// Interval between status checks, 2 seconds if 0
+	PollInterval time.Duration
+	// Called with the progress after every status check
+	Progress func(CampaignProgress)
+}
+
+// Wait until a launched campaign is finished, paused or cancelled, checking
+// its status and sent count every opts.PollInterval. Returns the last progress
+// seen. With WaitForCampaignContext, waiting stops when ctx expires and the
+// last progress is returned together with the context error. Waiting for a
+// campaign that has not been launched or scheduled fails with ErrValidation.
+// In dry-run mode the current progress is returned without waiting.
+func (c *APIClient) WaitForCampaign(id uint, opts WaitOptions) (*CampaignProgress, error) {
+	return c.WaitForCampaignContext(context.Background(), id, opts)
+}
+
+// WaitForCampaignContext is like WaitForCampaign but uses ctx for all Listmonk requests.
+func (c *APIClient) WaitForCampaignContext(ctx context.Context, id uint, opts WaitOptions) (*CampaignProgress, error) {
+	start := time.Now()
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	c.logInfo(ctx, "Waiting for campaign to finish.", "campaign_id", id)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var progress *CampaignProgress
+	for {
+		campaign, err := c.getCampaign(ctx, id)
+		if err != nil {
+			return progress, err
+		}
+		progress = &CampaignProgress{
+			CampaignID: campaign.Id,
+			Name:       campaign.Name,
+			Status:     campaign.Status,
+			Sent:       campaign.Sent,
+			ToSend:     campaign.ToSend,
+		}
+		if opts.Progress != nil {
+			opts.Progress(*progress)
+		}
+		if progress.Done() {
+			c.logOK(ctx, start, "Campaign stopped sending.", "campaign_id", id, "status", progress.Status, "sent", progress.Sent, "to_send", progress.ToSend)
+			return progress, nil
+		}
+		if c.plan != nil {
+			c.logInfo(ctx, "Dry run, not waiting for campaign.", "campaign_id", id, "status", progress.Status)
+			return progress, nil
+		}
+		if progress.Status == "draft" {
+			return progress, fmt.Errorf("%w: campaign %d has not been launched", ErrValidation, id)
+		}
+
+		select {
+		case <-ctx.Done():
+			c.logWarning(ctx, start, "Stopped waiting for campaign.", "campaign_id", id, "status", progress.Status, "sent", progress.Sent, "to_send", progress.ToSend)
+			return progress, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}